@@ -0,0 +1,247 @@
+// This binary is the client-end of the lego rubik's cube solver.
+// It runs on LEGO EV3 and connects to solver server.
+//
+// The positions of the motors and their ports are in a geometry profile, see
+// --geometry. Without --input, the cube is scanned: the eye reads an edge with
+// the turntable square (0, 270, 540, 810) and a corner with it an eighth back
+// (-135, 135, 405, 675).
+//
+// Colors: (RGB)
+//   w=195/236/237
+//   g=24/88/130, 36/155/182, 32/109/169, 25/79/111
+//   b=27/91/134, 25/91/133
+//   o=137/220/309, 87/133/210, 75/138, 236
+//   r=107/40/26, 72/38/27,
+//   y=73/115/145, 192/235/334
+//
+// Example input:
+//  "bwwbyryyr wrrrgyyoo ggboobygy wygwbyoro roobrgwwb bogbwwrgg"
+//   --------- --------- --------- --------- --------- ---------
+//      ^         ^        ^          ^         ^         ^
+//      |         |        |          |         |         |
+//     Up        Left    Front      Right      Back      Down
+//
+// Usage:
+// $ ./lego_cube --server=169.254.60.8 \
+//    --input='wrygoroog bybrgooor ybygwybww rbgrbyrwb owrbygwwg yggyrbwoo'
+// $ ./lego_cube --server=169.254.60.8
+//
+// The moves of the robot are in package robot, this binary connects it to the
+// motors and the color sensor of the EV3 through package robot/ev3.
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/robot"
+	"github.com/ross-wu/cube/robot/ev3"
+)
+
+var (
+	serverAddr      = flag.String("server", "", "Cube-solver server address and port.")
+	geometryFile    = flag.String("geometry", "", "JSON geometry profile of the robot, the built-in one if empty")
+	portOfFlipMotor = flag.String("flip_motor", "", "port of the flip motor, can be A, B, C, D, overrides the geometry if set.")
+	portOfTurnMotor = flag.String("turn_motor", "", "port of the turn motor, can be A, B, C, D, overrides the geometry if set.")
+	portOfEyeMotor  = flag.String("eye_motor", "", "port of the eye motor which controls the color sensor, can be A, B, C, D, overrides the geometry if set.")
+	eyeSensorPort   = flag.String("eye_sensor", "", "port of the eye sensor (color sensor), can be 1, 2, 3, 4, overrides the geometry if set")
+	profile         = flag.String("profile", robot.DefaultProfile, "motion profile: "+strings.Join(robot.ProfileNames(), ", "))
+	speedOfFlip     = flag.Int("flip_speed", 0, "flip motor speed, overrides the one of the profile if set")
+	speedOfTurn     = flag.Int("turn_speed", 0, "turn motor speed, overrides the one of the profile if set")
+	alignTolerance  = flag.Int("align_tolerance", 0, "degrees of the turn motor the turntable may stop off square before it's corrected, overrides the geometry if set")
+	retries         = flag.Int("retries", 2, "retries of a failed flip, turn or hold before the solve is aborted")
+	backoff         = flag.Duration("backoff", 500*time.Millisecond, "wait before the first retry of a failed motion, doubled for each next retry")
+	input           = flag.String("input", "", "the cube, scanned with the color sensor if empty, eg: 'gborrwyyw wwgobbowb ogrywyygr bggogbygo worrywyyw rogborbrb'")
+	test            = flag.Bool("test", false, "test")
+	calibrate       = flag.Bool("calibrate", false, "jog the motors from the keyboard to calibrate the robot, and write the --geometry profile")
+	debug           = flag.Bool("debug", false, "debug mode: step through the solve, and print the progress of the motors")
+)
+
+var faces map[string]string
+
+func tachoMotorOrDie(port string, driver ev3.MotorDriver) robot.Motor {
+	motor, err := ev3.TachoMotor(port, driver)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		os.Exit(255)
+	}
+	return motor
+}
+
+// loadGeometry loads the --geometry profile, with the ports and the tolerance
+// of the flags.
+func loadGeometry() robot.Geometry {
+	geo := robot.DefaultGeometry()
+	if *geometryFile != "" {
+		var err error
+		if geo, err = robot.LoadGeometry(*geometryFile); *calibrate && os.IsNotExist(err) {
+			// A new build is calibrated from the original robot.
+			geo = robot.DefaultGeometry()
+		} else if err != nil {
+			log.Printf("ERROR: %v", err)
+			os.Exit(1)
+		}
+	}
+	for _, f := range []struct {
+		flag string
+		port *string
+	}{
+		{*portOfFlipMotor, &geo.Ports.Flip},
+		{*portOfTurnMotor, &geo.Ports.Turn},
+		{*portOfEyeMotor, &geo.Ports.Eye},
+		{*eyeSensorPort, &geo.Ports.Sensor},
+	} {
+		if f.flag != "" {
+			*f.port = f.flag
+		}
+	}
+	if *alignTolerance != 0 {
+		geo.Turn.Tolerance = *alignTolerance
+	}
+	if err := geo.Validate(); err != nil {
+		log.Printf("ERROR: %v", err)
+		os.Exit(1)
+	}
+	return geo
+}
+
+// calibrateOrDie calibrates the robot, and writes the --geometry profile.
+func calibrateOrDie(r *robot.Robot) {
+	geo, err := r.Calibrate(os.Stdin)
+	r.Reset()
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		os.Exit(255)
+	}
+	if err := geo.Save(*geometryFile); err != nil {
+		log.Printf("ERROR: can't write the geometry profile: %v", err)
+		os.Exit(255)
+	}
+	fmt.Printf("Geometry written to %s: %+v\n", *geometryFile, geo)
+}
+
+// parseInput parses and validates the --input cube with the same model as the
+// server, so that a typo is caught before the robot moves.
+func parseInput(input string) {
+	c, err := cube.ParseCube(cube.FormatULFRBD, input)
+	if err != nil {
+		fmt.Printf("ERROR: wrong input: %v: %q\n", err, input)
+		os.Exit(255)
+	}
+	if err := c.Validate(); err != nil {
+		fmt.Printf("ERROR: invalid cube: %v\n", err)
+		os.Exit(255)
+	}
+	faces = c.Faces()
+}
+
+// scanInput scans the cube with the color sensor, and validates it like
+// parseInput.
+func scanInput(r *robot.Robot) {
+	c, err := r.Scan()
+	if err != nil {
+		r.Reset()
+		fmt.Printf("ERROR: scan aborted, the cube is released: %v\n", err)
+		os.Exit(255)
+	}
+	state, _ := c.Format(cube.FormatULFRBD)
+	fmt.Printf("Scanned: %s\n", state)
+	if err := c.Validate(); err != nil {
+		r.Reset()
+		fmt.Printf("ERROR: the scan misread the cube: %v, pass it as --input instead\n", err)
+		os.Exit(255)
+	}
+	faces = c.Faces()
+}
+
+func main() {
+	flag.Parse()
+
+	prof, err := robot.ProfileFor(*profile)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		os.Exit(1)
+	}
+	if *speedOfFlip != 0 {
+		prof.Flip.Speed = *speedOfFlip
+	}
+	if *speedOfTurn != 0 {
+		prof.Turn.Speed = *speedOfTurn
+	}
+	if *calibrate && *geometryFile == "" {
+		fmt.Println("ERROR: --geometry must be set to calibrate.")
+		os.Exit(1)
+	}
+	geo := loadGeometry()
+	opts := robot.Options{
+		Geometry: geo,
+		Profile:  prof,
+		Retries:  *retries,
+		Backoff:  *backoff,
+	}
+	if *debug {
+		opts.StepByStep = os.Stdin
+		opts.Debug = os.Stdout
+	}
+	sensor, err := ev3.ColorSensor(geo.Ports.Sensor)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		os.Exit(255)
+	}
+	r := robot.New(robot.Hardware{
+		Flip:   tachoMotorOrDie(geo.Ports.Flip, ev3.LargeMotor),
+		Turn:   tachoMotorOrDie(geo.Ports.Turn, ev3.LargeMotor),
+		Eye:    tachoMotorOrDie(geo.Ports.Eye, ev3.MediumMotor),
+		Sensor: sensor,
+	}, opts)
+	if err := r.Connect(); err != nil {
+		log.Printf("ERROR: %v", err)
+		os.Exit(255)
+	}
+
+	if *calibrate {
+		calibrateOrDie(r)
+		os.Exit(0)
+	}
+
+	if *test {
+		err = r.SelfTest()
+		r.Reset()
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			os.Exit(255)
+		}
+		os.Exit(0)
+	}
+
+	if *serverAddr == "" {
+		fmt.Println("ERROR: --server must be set.")
+		os.Exit(1)
+	}
+
+	if *input != "" {
+		parseInput(*input)
+	} else {
+		scanInput(r)
+	}
+
+	steps, err := robot.RequestMoves(*serverAddr, faces)
+	if err != nil {
+		fmt.Printf("ERROR: request server error: %v", err)
+		os.Exit(255)
+	}
+	fmt.Printf("Response: steps=%d: %v\n", len(steps), steps)
+	err = r.Solve(steps)
+	r.Reset()
+	if err != nil {
+		fmt.Printf("ERROR: solve aborted, the cube is released: %v\n", err)
+		os.Exit(255)
+	}
+	fmt.Printf("\nDONE\n")
+}
@@ -0,0 +1,325 @@
+// Versioned JSON API of the solver server.
+//
+//   POST /api/v1/solve
+//   {"faces": {"U": "yyoyygbwo", "L": "ggwooboob", "F": "rrwybwyoo",
+//              "R": "brgbrgyrg", "B": "wrrwgywoy", "D": "rbbgwbgwr"}}
+//
+// or with the whole cube in one of the formats of cube/format.go:
+//
+//   {"format": "facelets",
+//    "state": "UUUUUUUUURRRRRRRRRFFFFFFFFFDDDDDDDDDLLLLLLLLLBBBBBBBBB"}
+//
+// "maxDepth" limits the number of moves of the solution and "timeout" the
+// search time, as a duration like "5s" or a number of seconds:
+//
+//   {"faces": {...}, "maxDepth": 21, "timeout": "5s"}
+//
+// The response always has the same shape, and "error" is set only when the
+// request failed:
+//
+//   {"version": "v1",
+//    "state": {"U": "yyoyygbwo", ...},
+//    "solution": ["R'", "B'", ...],
+//    "moves": ["turn'", "flip", "D'", ...],
+//    "counts": {"flip": 20, "turn": 15, "d": 20},
+//    "unoptimizedCounts": {"flip": 20, "turn": 17, "d": 21},
+//    "estimatedSeconds": 91.35,
+//    "placement": "RB",
+//    "reason": "fastest of 24 placements: ...",
+//    "error": {"code": "invalid_face", "message": "...", "face": "U"}}
+//
+// "target" solves the cube into a preset pattern or another state instead,
+// see pattern.go:
+//
+//   {"faces": {...}, "target": "checkerboard"}
+//
+// A cube which can't be solved is rejected with one of the validation error
+// codes of cube/validate.go, e.g.
+//
+//   {"code": "twist_error", "message": "the corner at URF must be twisted clockwise"}
+//
+// A failed solve has a status and code for each outcome of the solver: 422
+// no_solution when there's no solution within maxDepth, 504 timeout, 502
+// solver_failed when the solver can't run, and 499 canceled when the client
+// went away.
+//
+// Before replying, the server replays the physical moves on the cube and
+// checks that they leave it solved, or in the target. A response which fails
+// the check is rejected with 500 not_solved instead of sending the robot
+// moves which would scramble the cube.
+//
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+)
+
+const apiVersion = "v1"
+
+// Error codes of the API.
+const (
+	ErrCodeBadMethod     = "method_not_allowed"
+	ErrCodeBadRequest    = "invalid_request"
+	ErrCodeInvalidFace   = "invalid_face"
+	ErrCodeApply         = "apply_failed"
+	ErrCodeNotSolved     = "not_solved"
+	ErrCodeInvalidMoves  = "invalid_moves"
+	ErrCodeInvalidTarget = "invalid_target"
+
+	// Solver failures, a cube which fails one of the solver's own checks
+	// gets the validation error code of cube/validate.go instead.
+	ErrCodeNoSolution   = "no_solution"
+	ErrCodeTimeout      = "timeout"
+	ErrCodeCanceled     = "canceled"
+	ErrCodeSolverFailed = "solver_failed"
+)
+
+// statusClientClosedRequest is the status of a solve canceled because the
+// client went away, so it's only logged.
+const statusClientClosedRequest = 499
+
+// solveErrors maps the codes of solver.Error to the HTTP status and the API
+// error code.
+var solveErrors = map[solver.Code]struct {
+	status int
+	code   string
+}{
+	solver.CodeColorCount: {http.StatusUnprocessableEntity, cube.ErrCodeColors},
+	solver.CodeEdges:      {http.StatusUnprocessableEntity, cube.ErrCodeEdges},
+	solver.CodeFlip:       {http.StatusUnprocessableEntity, cube.ErrCodeFlip},
+	solver.CodeCorners:    {http.StatusUnprocessableEntity, cube.ErrCodeCorners},
+	solver.CodeTwist:      {http.StatusUnprocessableEntity, cube.ErrCodeTwist},
+	solver.CodeParity:     {http.StatusUnprocessableEntity, cube.ErrCodeParity},
+	solver.CodeNoSolution: {http.StatusUnprocessableEntity, ErrCodeNoSolution},
+	solver.CodeTimeout:    {http.StatusGatewayTimeout, ErrCodeTimeout},
+	solver.CodeCanceled:   {statusClientClosedRequest, ErrCodeCanceled},
+	solver.CodeFailed:     {http.StatusBadGateway, ErrCodeSolverFailed},
+}
+
+// solveError returns the HTTP status and the API error of a failed solve. A
+// solution which doesn't solve the cube is a bug of the server, not of the
+// solver.
+func solveError(err error) (int, *APIError) {
+	switch {
+	case errors.Is(err, errNotSolved):
+		return http.StatusInternalServerError, &APIError{Code: ErrCodeNotSolved, Message: err.Error()}
+	case errors.Is(err, errApply):
+		return http.StatusInternalServerError, &APIError{Code: ErrCodeApply, Message: err.Error()}
+	}
+	e, ok := err.(*solver.Error)
+	if !ok {
+		return http.StatusInternalServerError, &APIError{Code: ErrCodeSolverFailed, Message: err.Error()}
+	}
+	m := solveErrors[e.Code]
+	return m.status, &APIError{Code: m.code, Message: err.Error()}
+}
+
+type SolveRequest struct {
+	// Faces maps the face codes U, L, F, R, B and D to 9 color letters each.
+	Faces map[string]string `json:"faces"`
+
+	// State is the whole cube in Format, it's used instead of Faces if set.
+	// An empty Format is detected from the state.
+	Format cube.Format `json:"format,omitempty"`
+	State  string      `json:"state,omitempty"`
+
+	// MaxDepth and Timeout limit the search, the solver defaults are used
+	// if they're not set.
+	MaxDepth int    `json:"maxDepth,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+
+	// Placements to solve, e.g. ["UF", "FR"] or ["all"], --placements if
+	// not set.
+	Placements []string `json:"placements,omitempty"`
+
+	// Target is the state to solve the cube into, a preset pattern like
+	// "checkerboard" or a state in any format. TargetFaces is the target as
+	// faces, used if Target isn't set. The cube is solved if neither is set.
+	Target      string            `json:"target,omitempty"`
+	TargetFaces map[string]string `json:"targetFaces,omitempty"`
+}
+
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Face    string `json:"face,omitempty"`
+}
+
+// MoveCounts counts the physical moves by primitive: turn includes turn, turn'
+// and turn2, d includes D, D' and D2.
+type MoveCounts struct {
+	Flip int `json:"flip"`
+	Turn int `json:"turn"`
+	D    int `json:"d"`
+}
+
+type SolveResponse struct {
+	Version  string            `json:"version"`
+	State    map[string]string `json:"state"`
+	Solution []string          `json:"solution"`
+	Moves    []string          `json:"moves"`
+	Counts   MoveCounts        `json:"counts"`
+	// UnoptimizedCounts counts the physical moves before the peephole
+	// optimizer.
+	UnoptimizedCounts MoveCounts `json:"unoptimizedCounts"`
+	// EstimatedSeconds is the estimated robot time of the moves.
+	EstimatedSeconds float64 `json:"estimatedSeconds"`
+	// Placement is the placement of the cube the solution is for, and
+	// Reason why it was chosen.
+	Placement string `json:"placement"`
+	Reason    string `json:"reason"`
+	// TargetState is the state the solution ends in if it isn't the
+	// solved cube.
+	TargetState map[string]string `json:"targetState,omitempty"`
+	Error       *APIError         `json:"error,omitempty"`
+}
+
+// CountMoves counts the physical moves by primitive.
+func CountMoves(moves []string) MoveCounts {
+	var n MoveCounts
+	for _, m := range moves {
+		switch m {
+		case cube.MoveFlip:
+			n.Flip++
+		case cube.MoveTurn1, cube.MoveTurn2, cube.MoveRTurn:
+			n.Turn++
+		case cube.MoveD, cube.MoveD2, cube.Moved:
+			n.D++
+		}
+	}
+	return n
+}
+
+func setCORSHeaders(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", "Content-Type")
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("ERROR: failed to write response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, resp *SolveResponse, e *APIError) {
+	log.Printf("ERROR: %s: %s", e.Code, e.Message)
+	resp.Error = e
+	writeJSON(w, status, resp)
+}
+
+func httpSolveV1(w http.ResponseWriter, req *http.Request) {
+	log.Printf("%s: %s %s", req.RemoteAddr, req.Method, req.URL.Path)
+	setCORSHeaders(w)
+
+	resp := &SolveResponse{Version: apiVersion}
+	switch req.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeAPIError(w, http.StatusMethodNotAllowed, resp, &APIError{
+			Code:    ErrCodeBadMethod,
+			Message: fmt.Sprintf("method %s is not allowed, use POST", req.Method),
+		})
+		return
+	}
+
+	var sreq SolveRequest
+	if err := json.NewDecoder(req.Body).Decode(&sreq); err != nil {
+		writeAPIError(w, http.StatusBadRequest, resp, &APIError{
+			Code:    ErrCodeBadRequest,
+			Message: fmt.Sprintf("can't decode request: %v", err),
+		})
+		return
+	}
+	resp.State = sreq.Faces
+	opts, err := solveOptions(sreq.MaxDepth, sreq.Timeout)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, resp, &APIError{
+			Code:    ErrCodeBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	names, err := parsePlacements(strings.Join(sreq.Placements, ","))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, resp, &APIError{
+			Code:    ErrCodeBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c, err := readCubeOrState(sreq.Format, sreq.State, func(k string) string {
+		return strings.TrimSpace(sreq.Faces[k])
+	})
+	if err != nil {
+		e := &APIError{Code: ErrCodeInvalidFace, Message: err.Error()}
+		if fe, ok := err.(*cube.FaceError); ok {
+			e.Face = fe.Face
+		}
+		writeAPIError(w, http.StatusBadRequest, resp, e)
+		return
+	}
+	resp.State = c.Faces()
+	if *verbose {
+		fmt.Println("Input cube:")
+		c.Print()
+	}
+	if err := c.Validate(); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, resp, &APIError{
+			Code:    err.(*cube.ValidationError).Code,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	target, err := readTarget(c, sreq.Target, func(k string) string {
+		return strings.TrimSpace(sreq.TargetFaces[k])
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, resp, &APIError{
+			Code:    ErrCodeInvalidTarget,
+			Message: err.Error(),
+		})
+		return
+	}
+	if target != nil {
+		resp.TargetState = target.Faces()
+	}
+
+	best, err := solveBest(req.Context(), c, target, opts, names)
+	if err != nil {
+		status, e := solveError(err)
+		writeAPIError(w, status, resp, e)
+		return
+	}
+	resp.Solution = best.Solution
+	resp.Placement = best.Name
+	resp.Reason = best.Reason
+	log.Printf("INFO: solution: step=%d: %s", len(resp.Solution), strings.Join(resp.Solution, " "))
+	log.Printf("INFO: placement %s: %s", best.Name, best.Reason)
+
+	moves, raw := best.Moves, best.Raw
+	resp.Moves = moves
+	resp.Counts = CountMoves(moves)
+	resp.UnoptimizedCounts = CountMoves(raw)
+	resp.EstimatedSeconds = cube.EstimateTime(moves).Seconds()
+	writeJSON(w, http.StatusOK, resp)
+
+	log.Printf("SUCCEEDED: move: %v", moves)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+)
+
+// apiCall sends the request to the JSON API, and returns the response with
+// its decoded body.
+func apiCall(t *testing.T, srv *httptest.Server, method, body string) (*http.Response, *SolveResponse) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+"/api/v1/solve", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s /api/v1/solve error: %v", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s /api/v1/solve: can't read the body: %v", method, err)
+	}
+	if len(data) == 0 {
+		return resp, nil
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s /api/v1/solve Content-Type %q, want application/json", method, ct)
+	}
+	// The body always has the same shape, error is set only on failures.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("%s /api/v1/solve body %s: %v", method, data, err)
+	}
+	for _, key := range []string{"version", "state", "solution", "moves", "counts", "unoptimizedCounts", "estimatedSeconds", "placement", "reason"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("%s /api/v1/solve body %s has no %q", method, data, key)
+		}
+	}
+	if _, ok := fields["error"]; ok != (resp.StatusCode != http.StatusOK) {
+		t.Errorf("%s /api/v1/solve status %d with body %s", method, resp.StatusCode, data)
+	}
+	var sresp SolveResponse
+	if err := json.Unmarshal(data, &sresp); err != nil {
+		t.Fatalf("%s /api/v1/solve body %s: %v", method, data, err)
+	}
+	if sresp.Version != apiVersion {
+		t.Errorf("%s /api/v1/solve version %q, want %q", method, sresp.Version, apiVersion)
+	}
+	return resp, &sresp
+}
+
+func checkCORS(t *testing.T, resp *http.Response) {
+	t.Helper()
+	for key, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Methods": "POST, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type",
+	} {
+		if got := resp.Header.Get(key); got != want {
+			t.Errorf("%s: %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSolveV1(t *testing.T) {
+	useNativeSolver(t)
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	c := cube.RandomCube(rand.New(rand.NewSource(1)))
+	body, err := json.Marshal(SolveRequest{Faces: c.Faces()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, sresp := apiCall(t, srv, http.MethodPost, string(body))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST status %d, want 200: %+v", resp.StatusCode, sresp.Error)
+	}
+	checkCORS(t, resp)
+	if len(sresp.Solution) == 0 || len(sresp.Moves) == 0 {
+		t.Errorf("POST solution %v and moves %v, want both", sresp.Solution, sresp.Moves)
+	}
+	if sresp.Counts != CountMoves(sresp.Moves) {
+		t.Errorf("POST counts %+v, want %+v", sresp.Counts, CountMoves(sresp.Moves))
+	}
+	// The fastest of the 24 placements of --placements.
+	if sresp.Placement == "" || !strings.HasPrefix(sresp.Reason, "fastest of 24 placements") || sresp.EstimatedSeconds <= 0 {
+		t.Errorf("POST placement %q, reason %q and estimated time %vs, want the fastest of 24 and some time", sresp.Placement, sresp.Reason, sresp.EstimatedSeconds)
+	}
+	for _, m := range sresp.Moves {
+		if err := c.Do(m); err != nil {
+			t.Fatalf("Do(%s) error: %v", m, err)
+		}
+	}
+	if !c.IsSolved() {
+		t.Errorf("the moves %v don't solve the cube", sresp.Moves)
+	}
+}
+
+// TestPreflight checks the CORS preflight of browsers.
+func TestPreflight(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+	resp, sresp := apiCall(t, srv, http.MethodOptions, "")
+	if resp.StatusCode != http.StatusNoContent || sresp != nil {
+		t.Errorf("OPTIONS status %d with body %+v, want 204 without a body", resp.StatusCode, sresp)
+	}
+	checkCORS(t, resp)
+}
+
+func TestSolveV1Errors(t *testing.T) {
+	useNativeSolver(t)
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	solved := "UUUUUUUUURRRRRRRRRFFFFFFFFFDDDDDDDDDLLLLLLLLLBBBBBBBBB"
+	// The URF corner of the solved cube twisted.
+	twisted := solved[:8] + "F" + "U" + solved[10:20] + "R" + solved[21:]
+	for _, tc := range []struct {
+		name, method, body string
+		status             int
+		code, face         string
+	}{
+		{"method", http.MethodGet, "", http.StatusMethodNotAllowed, ErrCodeBadMethod, ""},
+		{"json", http.MethodPost, `{"faces": `, http.StatusBadRequest, ErrCodeBadRequest, ""},
+		{"max depth", http.MethodPost, `{"state": "` + solved + `", "maxDepth": 99}`, http.StatusBadRequest, ErrCodeBadRequest, ""},
+		{"placements", http.MethodPost, `{"state": "` + solved + `", "placements": ["UU"]}`, http.StatusBadRequest, ErrCodeBadRequest, ""},
+		{"face", http.MethodPost, `{"faces": {"U": "yyoyygbw"}}`, http.StatusBadRequest, ErrCodeInvalidFace, "U"},
+		{"state", http.MethodPost, `{"format": "facelets", "state": "UUU"}`, http.StatusBadRequest, ErrCodeInvalidFace, ""},
+		{"twist", http.MethodPost, `{"format": "facelets", "state": "` + twisted + `"}`, http.StatusUnprocessableEntity, cube.ErrCodeTwist, ""},
+		{"target", http.MethodPost, `{"state": "` + solved + `", "target": "spiral"}`, http.StatusBadRequest, ErrCodeInvalidTarget, ""},
+	} {
+		resp, sresp := apiCall(t, srv, tc.method, tc.body)
+		checkCORS(t, resp)
+		if resp.StatusCode != tc.status || sresp == nil || sresp.Error == nil {
+			t.Errorf("%s: status %d with body %+v, want %d and an error", tc.name, resp.StatusCode, sresp, tc.status)
+			continue
+		}
+		if e := sresp.Error; e.Code != tc.code || e.Face != tc.face || e.Message == "" {
+			t.Errorf("%s: error %+v, want code %q, face %q and a message", tc.name, e, tc.code, tc.face)
+		}
+	}
+}
+
+// failingSolver fails each solve with its error.
+type failingSolver struct {
+	err error
+}
+
+func (s failingSolver) Solve(context.Context, string, solver.Options) ([]string, error) {
+	return nil, s.err
+}
+
+// wrongSolver returns the same solution for every cube.
+type wrongSolver []string
+
+func (s wrongSolver) Solve(context.Context, string, solver.Options) ([]string, error) {
+	return s, nil
+}
+
+// solveErrorCases are the errors of the solvers, with the status and the API
+// code they map to.
+var solveErrorCases = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{&solver.Error{Code: solver.CodeColorCount}, http.StatusUnprocessableEntity, cube.ErrCodeColors},
+	{&solver.Error{Code: solver.CodeEdges}, http.StatusUnprocessableEntity, cube.ErrCodeEdges},
+	{&solver.Error{Code: solver.CodeFlip}, http.StatusUnprocessableEntity, cube.ErrCodeFlip},
+	{&solver.Error{Code: solver.CodeCorners}, http.StatusUnprocessableEntity, cube.ErrCodeCorners},
+	{&solver.Error{Code: solver.CodeTwist}, http.StatusUnprocessableEntity, cube.ErrCodeTwist},
+	{&solver.Error{Code: solver.CodeParity}, http.StatusUnprocessableEntity, cube.ErrCodeParity},
+	{&solver.Error{Code: solver.CodeNoSolution}, http.StatusUnprocessableEntity, ErrCodeNoSolution},
+	{&solver.Error{Code: solver.CodeTimeout}, http.StatusGatewayTimeout, ErrCodeTimeout},
+	{&solver.Error{Code: solver.CodeCanceled}, statusClientClosedRequest, ErrCodeCanceled},
+	{&solver.Error{Code: solver.CodeFailed}, http.StatusBadGateway, ErrCodeSolverFailed},
+	{errors.New("max depth must be between 1 and 30"), http.StatusInternalServerError, ErrCodeSolverFailed},
+	{fmt.Errorf("%w, it ends as %q", errNotSolved, "ulfrbd"), http.StatusInternalServerError, ErrCodeNotSolved},
+	{fmt.Errorf("%w: %v", errApply, "no such move: Q"), http.StatusInternalServerError, ErrCodeApply},
+}
+
+func TestSolveError(t *testing.T) {
+	for _, tc := range solveErrorCases {
+		status, e := solveError(tc.err)
+		if status != tc.status || e.Code != tc.code || e.Message != tc.err.Error() {
+			t.Errorf("solveError(%v) = %d, %+v, want %d and %q", tc.err, status, e, tc.status, tc.code)
+		}
+	}
+}
+
+// TestSolveV1SolverErrors checks the statuses and the error bodies of the
+// JSON API when the solver fails.
+func TestSolveV1SolverErrors(t *testing.T) {
+	defer func(s solver.Solver) { cubeSolver = s }(cubeSolver)
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	body := `{"state": "DRLUUBFBRBLURRLRUBLRDDFDLFUFUFFDBRDUBRUFLLFDDBFLUBLRBD"}`
+	for _, tc := range solveErrorCases {
+		cubeSolver = failingSolver{tc.err}
+		resp, sresp := apiCall(t, srv, http.MethodPost, body)
+		if resp.StatusCode != tc.status || sresp == nil || sresp.Error == nil {
+			t.Errorf("%v: status %d with body %+v, want %d and an error", tc.err, resp.StatusCode, sresp, tc.status)
+			continue
+		}
+		if sresp.Error.Code != tc.code || sresp.Error.Message != tc.err.Error() {
+			t.Errorf("%v: error %+v, want code %q", tc.err, sresp.Error, tc.code)
+		}
+	}
+
+	// A solution which doesn't solve the cube.
+	cubeSolver = wrongSolver{"U"}
+	resp, sresp := apiCall(t, srv, http.MethodPost, body)
+	if resp.StatusCode != http.StatusInternalServerError || sresp.Error == nil || sresp.Error.Code != ErrCodeNotSolved {
+		t.Errorf("wrong solution: status %d with body %+v, want 500 %s", resp.StatusCode, sresp, ErrCodeNotSolved)
+	}
+
+	// A real solve which can't find a solution within maxDepth.
+	useNativeSolver(t)
+	resp, sresp = apiCall(t, srv, http.MethodPost, `{"state": "DRLUUBFBRBLURRLRUBLRDDFDLFUFUFFDBRDUBRUFLLFDDBFLUBLRBD", "maxDepth": 3}`)
+	if resp.StatusCode != http.StatusUnprocessableEntity || sresp.Error == nil || sresp.Error.Code != ErrCodeNoSolution {
+		t.Errorf("maxDepth 3: status %d with body %+v, want 422 %s", resp.StatusCode, sresp, ErrCodeNoSolution)
+	}
+}
@@ -0,0 +1,320 @@
+// This binary is a Rubik's cube resolver server.
+//
+// Usage:
+//   $ ./server
+//   then, in brower:
+//     http://localhost/cube?U=yyoyygbwo&L=ggwooboob&F=rrwybwyoo&R=brgbrgyrg&B=wrrwgywoy&D=rbbgwbgwr
+//
+// The cube can also be given in one of the formats of cube/format.go:
+//     http://localhost/cube?format=facelets&state=UUUUUUUUURRRRRRRRRFFFFFFFFFDDDDDDDDDLLLLLLLLLBBBBBBBBB
+//
+// or POST a JSON request to the versioned API, see api.go:
+//   $ curl -d '{"faces":{"U":"yyoyygbwo","L":"ggwooboob","F":"rrwybwyoo",
+//       "R":"brgbrgyrg","B":"wrrwgywoy","D":"rbbgwbgwr"}}' http://localhost/api/v1/solve
+//
+// /scramble returns the cube after a move sequence, see scramble.go:
+//     http://localhost/scramble?moves=R+U+R'+U'
+//
+// /random returns random cubes and their scrambles, see random.go:
+//     http://localhost/random?seed=42
+//
+// target solves the cube into a pattern instead, see pattern.go:
+//     http://localhost/cube?U=...&target=checkerboard
+//
+// maxDepth limits the number of moves of the solution, and timeout the search
+// time, e.g. &maxDepth=21&timeout=5s.
+//
+// The algorithm and move notations are described in
+// https://cube3x3.com/how-to-solve-a-rubiks-cube/
+//
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+)
+
+var (
+	port         = flag.Int("port", 80, "http server port")
+	kociemba     = flag.String("kociemba", "./kociemba/bin/kociemba", "Path to the Kociemba's Rubik's Cube solver binary.")
+	solverName   = flag.String("solver", "native", "Cube solver: native uses the Go solver, cgo runs the C solver in process, exec runs the --kociemba binary for each cube.")
+	cacheDir     = flag.String("cache_dir", "cache", "Directory of the cgo and native solvers' tables.")
+	workers      = flag.Int("workers", runtime.NumCPU(), "Max number of cubes solved concurrently.")
+	plannerName  = flag.String("planner", "search", "Planner of the physical moves: search finds the fastest moves for the robot, static uses one fixed sequence per face.")
+	placements   = flag.String("placements", "all", "Placements of the cube to solve, the fastest one for the robot is used: all for the 24 placements, which solves the cube 24 times, or a comma-separated list like UF,FR.")
+	optimize     = flag.Bool("optimize", true, "Optimize the physical moves with the peephole optimizer.")
+	solveTimeout = flag.Duration("solve_timeout", time.Minute, "Deadline of a solve, including the wait for a free worker. The timeout of a request can't exceed it.")
+	verbose      = flag.Bool("v", false, "Print the cube for each step.")
+	debug        = flag.Bool("debug", false, "debug mode.")
+)
+
+var cubeSolver solver.Solver
+
+// errNotSolved means that the physical moves of a solution don't leave the
+// cube solved, or in its target: a bug in the calibs or the primitives, which
+// would have the robot scramble the cube instead.
+var errNotSolved = errors.New("the physical moves don't solve the cube")
+
+// errApply means that the solution can't be planned into physical moves.
+var errApply = errors.New("can't apply solution")
+
+// solveOptions parses the maxDepth and timeout parameters of a request. The
+// timeout is a duration like "500ms", or a number of seconds.
+func solveOptions(maxDepth int, timeout string) (solver.Options, error) {
+	opts := solver.Options{MaxDepth: maxDepth}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			secs, serr := strconv.ParseFloat(timeout, 64)
+			if serr != nil {
+				return opts, fmt.Errorf("invalid timeout %q, must be a duration like 5s or a number of seconds", timeout)
+			}
+			d = time.Duration(secs * float64(time.Second))
+		}
+		if d <= 0 {
+			return opts, fmt.Errorf("timeout must be positive, but was %s", timeout)
+		}
+		opts.Timeout = d
+	}
+	return opts.WithDefaults()
+}
+
+// solve solves the cube into the target, or the solved cube if target is nil,
+// within the --solve_timeout deadline. ctx is the context of the request, so
+// that the solve is aborted when the client goes away. The target must be in
+// the same frame as the cube.
+func solve(ctx context.Context, c, target *cube.Cube, opts solver.Options) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, *solveTimeout)
+	defer cancel()
+
+	s := c.KociembaScramble()
+	if target != nil {
+		var err error
+		if s, err = cube.Patternize(s, target.KociembaScramble()); err != nil {
+			log.Printf("ERROR: failed to patternize %q: %v", c.KociembaScramble(), err)
+			return nil, err
+		}
+	}
+	log.Printf("INFO: solve: %s, max depth: %d, timeout: %v", s, opts.MaxDepth, opts.Timeout)
+	steps, err := cubeSolver.Solve(ctx, s, opts)
+	if err != nil {
+		log.Printf("ERROR: failed to solve %q: %v", s, err)
+		return nil, err
+	}
+	return steps, nil
+}
+
+// cubeOptions returns the options of the cubes of the requests: the
+// --planner, and the steps and primitives printed with -v and --debug.
+func cubeOptions() cube.Options {
+	opts := cube.Options{Planner: cube.Planner(*plannerName)}
+	if *verbose {
+		opts.Steps = os.Stdout
+	}
+	if *debug {
+		opts.Debug = os.Stdout
+	}
+	return opts
+}
+
+// applySolution applies the solution to the cube, and returns the physical
+// moves optimized if --optimize is set, and the moves before the
+// optimization. It fails with errApply if the solution can't be planned, and
+// with errNotSolved unless the moves leave the cube in the target, or solved
+// if target is nil.
+func applySolution(c, target *cube.Cube, steps []string) (moves, raw []string, err error) {
+	before := c.Clone()
+	if raw, err = c.Apply(steps); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errApply, err)
+	}
+	if err := checkSolved(c, target); err != nil {
+		return nil, nil, err
+	}
+	if !*optimize {
+		return raw, raw, nil
+	}
+	moves = cube.Optimize(raw)
+	if err := before.VerifyMoves(raw, moves); err != nil {
+		log.Printf("ERROR: the optimized moves are wrong, using the original ones: %v", err)
+		return raw, raw, nil
+	}
+	log.Printf("INFO: optimized %d physical moves %+v to %d %+v", len(raw), CountMoves(raw), len(moves), CountMoves(moves))
+	return moves, raw, nil
+}
+
+// checkSolved returns errNotSolved unless the cube is in the target up to a
+// rotation of the whole cube, or solved if target is nil.
+func checkSolved(c, target *cube.Cube) error {
+	if target == nil && c.IsSolved() || target != nil && c.Matches(target) {
+		return nil
+	}
+	state, _ := c.Format(cube.FormatULFRBD)
+	return fmt.Errorf("%w, it ends as %q", errNotSolved, state)
+}
+
+func newSolver() (solver.Solver, error) {
+	var s solver.Solver
+	var err error
+	switch *solverName {
+	case "exec":
+		s = &solver.Exec{Path: *kociemba}
+	case "cgo":
+		log.Printf("Loading solver tables from %q", *cacheDir)
+		s, err = solver.NewCgo(*cacheDir)
+	case "native":
+		log.Printf("Loading solver tables from %q", *cacheDir)
+		s, err = solver.NewNative(*cacheDir)
+	default:
+		err = fmt.Errorf("unknown solver %q, must be native, cgo or exec", *solverName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return solver.NewPool(s, *workers), nil
+}
+
+// readCubeOrState builds a cube from state in the given format if state is set,
+// or from the six faces returned by get otherwise. The cube has the options of
+// cubeOptions.
+func readCubeOrState(format cube.Format, state string, get func(string) string) (*cube.Cube, error) {
+	var c *cube.Cube
+	var err error
+	if state == "" {
+		if c, err = cube.ReadFaces(get); err != nil {
+			log.Printf("ERROR: ReadFaces() error: %v", err)
+		}
+	} else if c, err = cube.ParseCube(format, state); err != nil {
+		log.Printf("ERROR: ParseCube(%q, %q) error: %v", format, state, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, c.SetOptions(cubeOptions())
+}
+
+func httpCube(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	log.Printf("%s: %s %s", req.RemoteAddr, req.Method, req.URL.Path)
+
+	c, err := readCubeOrState(cube.Format(req.FormValue("format")), req.FormValue("state"), req.FormValue)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	maxDepth := 0
+	if v := req.FormValue("maxDepth"); v != "" {
+		if maxDepth, err = strconv.Atoi(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("invalid maxDepth %q", v)))
+			return
+		}
+	}
+	opts, err := solveOptions(maxDepth, req.FormValue("timeout"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	fmt.Println("Input cube:")
+	c.Print()
+
+	if err := c.Validate(); err != nil {
+		log.Printf("ERROR: invalid cube: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("invalid cube: %v", err)))
+		return
+	}
+
+	names, err := parsePlacements(req.FormValue("placements"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	target, err := readTarget(c, req.FormValue("target"), func(string) string { return "" })
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("%s: %v", ErrCodeInvalidTarget, err)))
+		return
+	}
+
+	best, err := solveBest(req.Context(), c, target, opts, names)
+	if err != nil {
+		status, e := solveError(err)
+		w.WriteHeader(status)
+		w.Write([]byte(fmt.Sprintf("%s: %s", e.Code, e.Message)))
+		return
+	}
+	steps := best.Solution
+	solution := fmt.Sprintf("step=%d: %s", len(steps), strings.Join(steps, " "))
+	log.Printf("INFO: solution: %s\n", solution)
+	log.Printf("INFO: placement %s: %s", best.Name, best.Reason)
+
+	moves := best.Moves
+	w.Write([]byte(fmt.Sprintf("OK: %s %v", solution, moves)))
+	if !*verbose {
+		best.Cube.Print()
+	}
+
+	log.Printf("SUCCEEDED: move: %v", moves)
+}
+
+// newMux returns the handlers of the server.
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cube", httpCube)
+	mux.HandleFunc("/api/v1/solve", httpSolveV1)
+	mux.HandleFunc("/scramble", httpScramble)
+	mux.HandleFunc("/random", httpRandom)
+	return mux
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.Arg(0) == "random" {
+		var err error
+		if cubeSolver, err = newSolver(); err != nil {
+			log.Fatalf("ERROR: can't create solver: %v", err)
+		}
+		randomMain(flag.Args()[1:])
+		return
+	}
+
+	fmt.Println("Please set colors of each pieces on each face.")
+	fmt.Println("Colors are: White Red Green Blue Yellow Orange, or w r g b y o.")
+	fmt.Println("(input 9 whitespace-separated colors for each face):")
+
+	if *debug {
+		*verbose = true
+	}
+
+	if _, err := cube.New(cubeOptions()); err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	if _, err := cube.ParsePlacements(*placements); err != nil {
+		log.Fatalf("ERROR: --placements: %v", err)
+	}
+
+	var err error
+	if cubeSolver, err = newSolver(); err != nil {
+		log.Fatalf("ERROR: can't create solver: %v", err)
+	}
+
+	log.Printf("Starting http server on port %d", *port)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), newMux()))
+}
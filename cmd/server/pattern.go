@@ -0,0 +1,62 @@
+// Targets of a solve.
+//
+// The target is a preset name of cube/pattern.go, a cube state in one of the
+// formats of cube/format.go, or faces like the input of /cube:
+//
+//   http://localhost/cube?U=...&target=checkerboard
+//   {"faces": {...}, "target": "superflip"}
+//   {"faces": {...}, "targetFaces": {"U": "wywywywyw", ...}}
+//
+// The target must have the same centers as the cube.
+//
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ross-wu/cube/cube"
+)
+
+// readTarget reads the target of the cube c: the preset pattern or the state
+// named by target, or else the faces returned by get. It returns nil if there
+// is no target, and an error if the target isn't a valid cube with the
+// centers of c.
+func readTarget(c *cube.Cube, target string, get func(string) string) (*cube.Cube, error) {
+	var t *cube.Cube
+	var err error
+	name := strings.ToLower(target)
+	_, preset := cube.Patterns[name]
+	switch {
+	case preset:
+		t, err = cube.PatternCube(name, c)
+	case target != "":
+		t, err = cube.ParseCube("", target)
+	case hasFaces(get):
+		t, err = cube.ReadFaces(get)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %v", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid target: %v", err)
+	}
+	for _, code := range cube.FaceCodes() {
+		if want, got := c.Face(code).Pieces[4], t.Face(code).Pieces[4]; got != want {
+			return nil, fmt.Errorf("invalid target: the center of the %s face must be %s like the cube, but was %s",
+				cube.FaceName(code), want, got)
+		}
+	}
+	return t, nil
+}
+
+func hasFaces(get func(string) string) bool {
+	for _, code := range cube.FaceCodes() {
+		if get(string(code)) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+// Solving the cube in each of its placements in the robot, see
+// cube/placement.go. solveBest solves the cube in each of the 24 placements,
+// plans each solution on the real cube through calibs, and keeps the one with
+// the lowest estimated robot time.
+//
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+)
+
+// placement is the solution of the cube in one placement.
+type placement struct {
+	Name     string
+	Solution []string
+	// Moves are the optimized physical moves on the real cube, Raw the moves
+	// before the optimization, and Cube the real cube after the moves.
+	Moves, Raw []string
+	Cube       *cube.Cube
+	Time       time.Duration
+	// Reason tells why the placement was chosen.
+	Reason string
+	// steps and debug are the output of the cube's Steps and Debug, which
+	// solveBest writes out for the best placement only.
+	steps, debug bytes.Buffer
+}
+
+// parsePlacements parses the placements parameter of a request, --placements
+// if it's empty.
+func parsePlacements(s string) ([]string, error) {
+	if s == "" {
+		s = *placements
+	}
+	return cube.ParsePlacements(s)
+}
+
+func solvePlacement(ctx context.Context, c *cube.Cube, name string, target *cube.Cube, opts solver.Options) (*placement, error) {
+	rot, err := c.Place(name)
+	if err != nil {
+		return nil, err
+	}
+	rotTarget := target
+	if target != nil {
+		// The target is rotated like the cube, so that the solution
+		// still ends in the target as placed.
+		if rotTarget, err = target.Place(name); err != nil {
+			return nil, err
+		}
+	}
+	steps, err := solve(ctx, rot, rotTarget, opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &placement{Name: name, Solution: steps}
+	real := c.Clone()
+	real.UsePlacement(name)
+	// The placements are applied concurrently, so each keeps its output.
+	copts := c.Options()
+	if copts.Steps != nil {
+		copts.Steps = &p.steps
+	}
+	if copts.Debug != nil {
+		copts.Debug = &p.debug
+	}
+	real.SetOptions(copts)
+	if p.Moves, p.Raw, err = applySolution(real, target, steps); err != nil {
+		return nil, err
+	}
+	p.Cube = real
+	p.Time = cube.EstimateTime(p.Moves)
+	return p, nil
+}
+
+// solveBest solves the cube into the target, nil for the solved cube, in each
+// of the named placements concurrently, and returns the one with the lowest
+// estimated robot time, with the moves already applied and verified on the
+// cube. It fails only if all the placements fail, with the most specific of
+// their errors, see placementError.
+func solveBest(ctx context.Context, c, target *cube.Cube, opts solver.Options, names []string) (*placement, error) {
+	ctx, cancel := context.WithTimeout(ctx, *solveTimeout)
+	defer cancel()
+
+	results := make([]*placement, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i], errs[i] = solvePlacement(ctx, c, name, target, opts)
+		}(i, name)
+	}
+	wg.Wait()
+
+	var best, placed *placement
+	failed := 0
+	for i, p := range results {
+		if p == nil {
+			log.Printf("ERROR: placement %s failed: %v", names[i], errs[i])
+			failed++
+			continue
+		}
+		if best == nil || p.Time < best.Time || p.Time == best.Time && len(p.Moves) < len(best.Moves) {
+			best = p
+		}
+		if p.Name == "UF" {
+			placed = p
+		}
+	}
+	if best == nil {
+		return nil, placementError(errs)
+	}
+	if copts := c.Options(); copts.Steps != nil {
+		copts.Steps.Write(best.steps.Bytes())
+	}
+	if copts := c.Options(); copts.Debug != nil {
+		copts.Debug.Write(best.debug.Bytes())
+	}
+
+	flips := CountMoves(best.Moves).Flip
+	switch {
+	case len(names) == 1:
+		best.Reason = fmt.Sprintf("only placement %s was tried: %v estimated robot time, %d flips", best.Name, best.Time, flips)
+	case placed == nil:
+		best.Reason = fmt.Sprintf("fastest of %d placements: %v estimated robot time, %d flips", len(names), best.Time, flips)
+	default:
+		best.Reason = fmt.Sprintf("fastest of %d placements: %v estimated robot time and %d flips, vs %v and %d flips as placed (UF)",
+			len(names), best.Time, flips, placed.Time, CountMoves(placed.Moves).Flip)
+	}
+	if failed > 0 {
+		best.Reason += fmt.Sprintf(", %d placements failed", failed)
+	}
+	return best, nil
+}
+
+// placementError returns the most specific of the errors of the placements:
+// an error about the cube, then a bug of the server, then a failure of the
+// solver, and a timeout or a cancellation last, since it may only hide why the
+// other placements failed.
+func placementError(errs []error) error {
+	rank := func(err error) int {
+		var e *solver.Error
+		if !errors.As(err, &e) {
+			if errors.Is(err, errNotSolved) || errors.Is(err, errApply) {
+				return 1
+			}
+			return 3
+		}
+		switch e.Code {
+		case solver.CodeFailed:
+			return 2
+		case solver.CodeTimeout, solver.CodeCanceled:
+			return 4
+		}
+		return 0
+	}
+	best := errs[0]
+	for _, err := range errs[1:] {
+		if rank(err) < rank(best) {
+			best = err
+		}
+	}
+	return best
+}
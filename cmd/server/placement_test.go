@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+)
+
+// useNativeSolver solves the cubes of the tests with the native solver.
+func useNativeSolver(t *testing.T) {
+	t.Helper()
+	if _, ok := cubeSolver.(*solver.Native); ok {
+		return
+	}
+	var err error
+	if cubeSolver, err = solver.NewNative("../../cache"); err != nil {
+		t.Fatalf("NewNative() error: %v", err)
+	}
+}
+
+// TestSolveBest checks that solveBest keeps the placement with the lowest
+// estimated robot time, and that its moves solve the cube as it is.
+func TestSolveBest(t *testing.T) {
+	useNativeSolver(t)
+	opts, err := solver.Options{}.WithDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+	names := []string{"UF", "FR", "RU", "DB", "LD"}
+	for i := 0; i < 3; i++ {
+		c := cube.RandomCube(r)
+		var steps bytes.Buffer
+		c.SetOptions(cube.Options{Steps: &steps})
+		best, err := solveBest(context.Background(), c, nil, opts, names)
+		if err != nil {
+			t.Fatalf("solveBest(%v) error: %v", names, err)
+		}
+		if !best.Cube.IsSolved() || len(best.Raw) < len(best.Moves) {
+			t.Errorf("solveBest(%v) = %s, its cube isn't solved or its raw moves %v are shorter than %v", names, best.Name, best.Raw, best.Moves)
+		}
+		// The steps of the best placement only.
+		if n := strings.Count(steps.String(), "calibs:"); n != len(best.Solution) {
+			t.Errorf("solveBest(%v) wrote the steps of %d moves, want the %d of %s", names, n, len(best.Solution), best.Name)
+		}
+		for _, name := range names {
+			p, err := solvePlacement(context.Background(), c, name, nil, opts)
+			if err != nil {
+				t.Fatalf("solvePlacement(%s) error: %v", name, err)
+			}
+			if p.Time < best.Time {
+				t.Errorf("solveBest(%v) = %s in %v, but %s takes %v", names, best.Name, best.Time, name, p.Time)
+			}
+		}
+		if !strings.HasPrefix(best.Reason, "fastest of 5 placements") {
+			t.Errorf("solveBest(%v) reason %q, want the fastest of 5", names, best.Reason)
+		}
+		robot := c.Clone()
+		for _, m := range best.Moves {
+			if err := robot.Do(m); err != nil {
+				t.Fatalf("Do(%s) error: %v", m, err)
+			}
+		}
+		if !robot.IsSolved() {
+			t.Errorf("the moves %v of placement %s don't solve the cube", best.Moves, best.Name)
+		}
+	}
+
+	best, err := solveBest(context.Background(), cube.RandomCube(r), nil, opts, []string{"UF"})
+	if err != nil {
+		t.Fatalf("solveBest(UF) error: %v", err)
+	}
+	if best.Name != "UF" || !strings.HasPrefix(best.Reason, "only placement UF") {
+		t.Errorf("solveBest(UF) = %s, %q, want UF only", best.Name, best.Reason)
+	}
+}
+
+func TestPlacementError(t *testing.T) {
+	timeout := &solver.Error{Code: solver.CodeTimeout}
+	canceled := &solver.Error{Code: solver.CodeCanceled}
+	failed := &solver.Error{Code: solver.CodeFailed}
+	noSolution := &solver.Error{Code: solver.CodeNoSolution}
+	notSolved := fmt.Errorf("%w, it ends as %q", errNotSolved, "ulfrbd")
+	other := errors.New("unknown placement")
+	for _, tc := range []struct {
+		errs []error
+		want error
+	}{
+		{[]error{timeout}, timeout},
+		{[]error{timeout, canceled, noSolution, failed}, noSolution},
+		{[]error{canceled, timeout, failed, notSolved}, notSolved},
+		{[]error{canceled, failed, timeout}, failed},
+		{[]error{timeout, other}, other},
+		{[]error{timeout, canceled}, timeout},
+	} {
+		if got := placementError(tc.errs); got != tc.want {
+			t.Errorf("placementError(%v) = %v, want %v", tc.errs, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Random cube states, for demos, robot soak tests and regression suites.
+//
+//   GET /random?seed=42&count=3
+//
+// returns uniformly random solvable cubes in the color scheme of /scramble,
+// and a scramble which reaches each of them from the solved cube, found by
+// solving the cube and inverting the solution:
+//
+//   {"seed": 42,
+//    "states": [{"faces": {"U": "gowbwrybo", ...},
+//                "query": "B=...&D=...&F=...&L=...&R=...&U=...",
+//                "facelets": "FLDBUR...",
+//                "scramble": ["R", "F2", "D'", ...]}, ...]}
+//
+// The same seed always gives the same states. Without a seed a new one is
+// picked and returned. The same is available from the command line:
+//
+//   $ ./server random --seed=42 --count=3
+//
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+)
+
+// maxRandomCount limits the number of states of one /random request.
+const maxRandomCount = 100
+
+// RandomState is a random cube and a scramble which reaches it.
+type RandomState struct {
+	Faces    map[string]string `json:"faces"`
+	Query    string            `json:"query"`
+	Facelets string            `json:"facelets"`
+	Scramble []string          `json:"scramble"`
+}
+
+// RandomResponse is the response of /random.
+type RandomResponse struct {
+	Seed   int64         `json:"seed"`
+	States []RandomState `json:"states"`
+	Error  *APIError     `json:"error,omitempty"`
+}
+
+// randomStates returns count random states drawn with the seed, and their
+// scrambles.
+func randomStates(ctx context.Context, seed int64, count int) ([]RandomState, error) {
+	r := rand.New(rand.NewSource(seed))
+	states := make([]RandomState, 0, count)
+	for i := 0; i < count; i++ {
+		c := cube.RandomCube(r)
+		steps, err := solve(ctx, c, nil, solver.Options{})
+		if err != nil {
+			return nil, err
+		}
+		s := RandomState{
+			Faces:    c.Faces(),
+			Scramble: cube.InvertMoves(steps),
+		}
+		query := url.Values{}
+		for code, letters := range s.Faces {
+			query.Set(code, letters)
+		}
+		s.Query = query.Encode()
+		s.Facelets, _ = c.Facelets()
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+// parseSeed parses a seed, or picks a new one if s is empty.
+func parseSeed(s string) (int64, error) {
+	if s == "" {
+		return time.Now().UnixNano(), nil
+	}
+	seed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid seed %q", s)
+	}
+	return seed, nil
+}
+
+func httpRandom(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	log.Printf("%s: %s %s", req.RemoteAddr, req.Method, req.URL.Path)
+
+	resp := &RandomResponse{States: []RandomState{}}
+	seed, err := parseSeed(req.FormValue("seed"))
+	count := 1
+	if err == nil && req.FormValue("count") != "" {
+		count, err = strconv.Atoi(req.FormValue("count"))
+		if err != nil || count < 1 || count > maxRandomCount {
+			err = fmt.Errorf("invalid count %q, must be 1 to %d", req.FormValue("count"), maxRandomCount)
+		}
+	}
+	if err != nil {
+		resp.Error = &APIError{Code: ErrCodeBadRequest, Message: err.Error()}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	resp.Seed = seed
+
+	ctx, cancel := context.WithTimeout(req.Context(), *solveTimeout)
+	defer cancel()
+	states, err := randomStates(ctx, seed, count)
+	if err != nil {
+		status, e := solveError(err)
+		log.Printf("ERROR: %s: %s", e.Code, e.Message)
+		resp.Error = e
+		writeJSON(w, status, resp)
+		return
+	}
+	resp.States = states
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// randomMain is the random subcommand, which prints random states and their
+// scrambles.
+func randomMain(args []string) {
+	fs := flag.NewFlagSet("random", flag.ExitOnError)
+	seedFlag := fs.String("seed", "", "Seed of the random states, a new one if empty.")
+	count := fs.Int("count", 1, "Number of random states.")
+	fs.Parse(args)
+
+	seed, err := parseSeed(*seedFlag)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	states, err := randomStates(context.Background(), seed, *count)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	fmt.Printf("seed: %d\n", seed)
+	for _, s := range states {
+		c, _ := cube.ParseCube(cube.FormatFacelets, s.Facelets)
+		state, _ := c.Format(cube.FormatULFRBD)
+		fmt.Printf("\nstate: %s\nfacelets: %s\nscramble: %s\n", state, s.Facelets, strings.Join(s.Scramble, " "))
+		if *verbose {
+			c.Print()
+		}
+	}
+}
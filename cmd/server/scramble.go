@@ -0,0 +1,97 @@
+// Scrambles: the cube state after a move sequence.
+//
+//   GET /scramble?moves=R+U+R'+U'
+//
+// applies the moves in WCA notation to a solved cube, white up and green
+// front, and returns the faces in the input format of /cube, the 54-facelet
+// string and renderings of the cube:
+//
+//   {"moves": ["R", "U", "R'", "U'"],
+//    "faces": {"U": "wwowwgwwg", ...},
+//    "query": "B=...&D=...&F=...&L=...&R=...&U=...",
+//    "facelets": "UUBUULUUL...",
+//    "ansi": "...",
+//    "svg": "<svg ...>...</svg>"}
+//
+// output=svg or output=ansi returns only that rendering, e.g. to show the
+// cube in a browser.
+//
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/ross-wu/cube/cube"
+)
+
+// ScrambleResponse is the response of /scramble.
+type ScrambleResponse struct {
+	Moves []string `json:"moves"`
+	// Faces are the 9 color letters of each face, the input of /cube, and
+	// Query the same as a query string.
+	Faces    map[string]string `json:"faces,omitempty"`
+	Query    string            `json:"query,omitempty"`
+	Facelets string            `json:"facelets,omitempty"`
+	ANSI     string            `json:"ansi,omitempty"`
+	SVG      string            `json:"svg,omitempty"`
+	Error    *APIError         `json:"error,omitempty"`
+}
+
+func httpScramble(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	log.Printf("%s: %s %s", req.RemoteAddr, req.Method, req.URL.Path)
+
+	resp := &ScrambleResponse{Moves: []string{}}
+	output := req.FormValue("output")
+	switch output {
+	case "", "json", "svg", "ansi":
+	default:
+		resp.Error = &APIError{
+			Code:    ErrCodeBadRequest,
+			Message: fmt.Sprintf("unknown output %q, must be json, svg or ansi", output),
+		}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	c := cube.SolvedCube()
+	moves, err := c.Scramble(req.FormValue("moves"))
+	if err != nil {
+		log.Printf("ERROR: %s: %v", ErrCodeInvalidMoves, err)
+		resp.Error = &APIError{Code: ErrCodeInvalidMoves, Message: err.Error()}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	var ansi bytes.Buffer
+	c.Fprint(&ansi)
+
+	switch output {
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte(c.SVG()))
+		return
+	case "ansi":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(ansi.Bytes())
+		return
+	}
+
+	for _, m := range moves {
+		resp.Moves = append(resp.Moves, string(m))
+	}
+	resp.Faces = c.Faces()
+	query := url.Values{}
+	for code, letters := range resp.Faces {
+		query.Set(code, letters)
+	}
+	resp.Query = query.Encode()
+	// The 6 centers always have different colors, so Facelets can't fail.
+	resp.Facelets, _ = c.Facelets()
+	resp.ANSI = ansi.String()
+	resp.SVG = c.SVG()
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/rand"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/robot"
+	"github.com/ross-wu/cube/robot/fake"
+)
+
+// TestSimulatedRobot solves random cubes with the moves of the robot client
+// on a simulated robot, through the handlers of the server and a real solver.
+func TestSimulatedRobot(t *testing.T) {
+	useNativeSolver(t)
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 3; i++ {
+		sim := fake.NewRobot(cube.RandomCube(r))
+		rb := robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep})
+		if err := rb.Connect(); err != nil {
+			t.Fatalf("Connect() error: %v", err)
+		}
+		scanned, err := rb.Scan()
+		if err != nil {
+			t.Fatalf("Scan() error: %v", err)
+		}
+		start := scanned.Faces()
+		steps, err := robot.RequestMoves(addr, start)
+		if err != nil {
+			t.Fatalf("RequestMoves(%v) error: %v", start, err)
+		}
+		if err := rb.Solve(steps); err != nil {
+			t.Fatalf("Solve(%v) error: %v", steps, err)
+		}
+		rb.Reset()
+		for _, err := range sim.Errors {
+			t.Errorf("the robot can't move like this: %v", err)
+		}
+		if !sim.Cube.IsSolved() {
+			state, _ := sim.Cube.Format(cube.FormatULFRBD)
+			t.Errorf("the moves %v of %v leave the cube as %s", steps, start, state)
+		}
+	}
+}
@@ -1,33 +1,28 @@
-// This binary is a Rubik's cube resolver server.
+// Package cube is the model of a Rubik's cube in the LEGO robot: the stickers
+// of each face, the moves in WCA notation, and the physical moves of the robot
+// which perform them.
 //
-// Usage:
-//   $ ./server
-//   then, in brower:
-//     http://localhost/cube?U=yyoyygbwo&L=ggwooboob&F=rrwybwyoo&R=brgbrgyrg&B=wrrwgywoy&D=rbbgwbgwr
+// The robot only turns the down face, and flips and turns the whole cube to
+// bring the other faces down. Cube keeps the faces where they physically are,
+// and calibs maps each virtual face of the solution to its physical position.
 //
-// The algorithm and move notations are described in
-// https://cube3x3.com/how-to-solve-a-rubiks-cube/
+//   c, err := cube.ParseCube(cube.FormatULFRBD, "wwwwwwwww ooooooooo ...")
+//   moves, err := c.Apply([]string{"R", "U'", "F2"})
 //
-package main
+// moves are the primitives of the robot: flip, turn, turn', turn2, D, D' and
+// D2.
+//
+package cube
 
 import (
-	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"io"
 	"os"
-	"os/exec"
 	"strings"
 )
 
-var (
-	port     = flag.Int("port", 80, "http server port")
-	kociemba = flag.String("kociemba", "./kociemba/bin/kociemba", "Path to the Kociemba's Rubik's Cube solver binary.")
-	verbose  = flag.Bool("v", false, "Print the cube for each step.")
-	debug    = flag.Bool("debug", false, "debug mode.")
-)
-
 type Color int
+
 type Move string
 
 const (
@@ -80,16 +75,45 @@ var (
 		Back:  Right,
 		Right: Front,
 	}
+
+	// flipCodes and turnCodes map the position of a face before a flip or a
+	// turn to its position after.
+	flipCodes = map[byte]byte{
+		Up:    Back,
+		Front: Up,
+		Down:  Front,
+		Back:  Down,
+	}
+	turnCodes = map[byte]byte{
+		Left:  Back,
+		Front: Left,
+		Right: Front,
+		Back:  Right,
+	}
 )
 
 type Face struct {
 	Pieces [9]Color
 }
 
+// String returns the name of the color, e.g. "white".
+func (c Color) String() string {
+	if name, ok := colorNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 func FaceName(code byte) string {
 	return faceNames[code]
 }
 
+// FaceCodes returns the codes of the faces in the order of ReadFaces and
+// FormatULFRBD: U, L, F, R, B and D.
+func FaceCodes() []byte {
+	return append([]byte(nil), faceCodes...)
+}
+
 type Cube struct {
 	faces map[byte]*Face
 	moves map[Move]func(*Cube) []string
@@ -98,6 +122,19 @@ type Cube struct {
 	// solving algoritm, I'll let the virual cube steady, so use var calibs to keep
 	// the mapping between virtual face to the real face.
 	calibs map[byte]byte
+
+	opts Options
+}
+
+// Options configure a Cube.
+type Options struct {
+	// Planner plans the physical moves of Apply, PlannerSearch if empty.
+	Planner Planner
+	// Steps, if set, gets the calibs, the physical moves and the cube after
+	// each move of Apply.
+	Steps io.Writer
+	// Debug, if set, gets the cube after each flip and turn.
+	Debug io.Writer
 }
 
 func rotateClock(f *Face) {
@@ -155,21 +192,14 @@ func (c *Cube) flip() *Cube {
 	rotateCounterclock(c.faces[Left])
 
 	// Update calibs.
-	for code := range c.calibs {
-		switch c.calibs[code] {
-		case Up:
-			c.calibs[code] = Back
-		case Front:
-			c.calibs[code] = Up
-		case Down:
-			c.calibs[code] = Front
-		case Back:
-			c.calibs[code] = Down
+	for code, pos := range c.calibs {
+		if to, ok := flipCodes[pos]; ok {
+			c.calibs[code] = to
 		}
 	}
-	if *debug {
-		fmt.Printf("flip:\n")
-		c.Print()
+	if c.opts.Debug != nil {
+		fmt.Fprintf(c.opts.Debug, "flip:\n")
+		c.Fprint(c.opts.Debug)
 	}
 	return c
 }
@@ -189,22 +219,15 @@ func (c *Cube) turn(n int) *Cube {
 		rotateCounterclock(c.faces[Down])
 
 		// Update calibs.
-		for code := range c.calibs {
-			switch c.calibs[code] {
-			case Left:
-				c.calibs[code] = Back
-			case Front:
-				c.calibs[code] = Left
-			case Right:
-				c.calibs[code] = Front
-			case Back:
-				c.calibs[code] = Right
+		for code, pos := range c.calibs {
+			if to, ok := turnCodes[pos]; ok {
+				c.calibs[code] = to
 			}
 		}
 	}
-	if *debug {
-		fmt.Printf("turn=%d:\n", n)
-		c.Print()
+	if c.opts.Debug != nil {
+		fmt.Fprintf(c.opts.Debug, "turn=%d:\n", n)
+		c.Fprint(c.opts.Debug)
 	}
 	return c
 }
@@ -337,9 +360,37 @@ func NewCube() *Cube {
 			Back:  Back,
 			Down:  Down,
 		},
+		opts: Options{Planner: PlannerSearch},
 	}
 }
 
+// New returns a cube without faces with the options.
+func New(opts Options) (*Cube, error) {
+	c := NewCube()
+	if err := c.SetOptions(opts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetOptions sets the options of the cube.
+func (c *Cube) SetOptions(opts Options) error {
+	switch opts.Planner {
+	case "":
+		opts.Planner = PlannerSearch
+	case PlannerStatic, PlannerSearch:
+	default:
+		return fmt.Errorf("unknown planner %q, must be one of %v", opts.Planner, Planners)
+	}
+	c.opts = opts
+	return nil
+}
+
+// Options returns the options of the cube.
+func (c *Cube) Options() Options {
+	return c.opts
+}
+
 func (c *Cube) Calib(old Move) Move {
 	b := []byte(old)
 	b[0] = c.calibs[b[0]]
@@ -347,12 +398,25 @@ func (c *Cube) Calib(old Move) Move {
 }
 
 // Rotate rotates cube fase according to the given move. The move should be the virtual face.
+// Slice moves, wide moves and rotations are supported, see wca.go.
 func (c *Cube) Rotate(m Move) ([]string, error) {
-	op, ok := c.moves[c.Calib(m)]
-	if !ok {
-		return nil, fmt.Errorf("no such move: %s", m)
+	parts, err := expandMove(m)
+	if err != nil {
+		return nil, err
 	}
-	return op(c), nil
+	pMoves := []string{}
+	for _, p := range parts {
+		if isRotation(p) {
+			c.rotate(p)
+			continue
+		}
+		op, ok := c.moves[c.Calib(p)]
+		if !ok {
+			return nil, fmt.Errorf("no such move: %s", m)
+		}
+		pMoves = append(pMoves, op(c)...)
+	}
+	return pMoves, nil
 }
 
 func (c *Cube) KociembaScramble() string {
@@ -380,25 +444,52 @@ func (c *Cube) CalibsDebugString() string {
 }
 
 // Apply applys the solution and output the physical movements.
-func (c *Cube) Apply(moves []string, printStep bool) ([]string, error) {
-	pMoves := []string{}
+func (c *Cube) Apply(moves []string) ([]string, error) {
+	var steps []Move
 	for i := range moves {
-		m := Move(strings.TrimSpace(moves[i]))
-		if len(m) == 0 {
-			continue
+		if m := Move(strings.TrimSpace(moves[i])); len(m) > 0 {
+			steps = append(steps, m)
 		}
-		if *verbose {
-			fmt.Printf("calibs: %s\n", c.CalibsDebugString())
+	}
+	var plan [][]string
+	if c.opts.Planner == PlannerSearch {
+		var err error
+		if plan, err = c.plan(steps); err != nil {
+			return nil, fmt.Errorf("can't plan %v: %w", steps, err)
+		}
+	}
+
+	pMoves := []string{}
+	for i, m := range steps {
+		if c.opts.Steps != nil {
+			fmt.Fprintf(c.opts.Steps, "calibs: %s\n", c.CalibsDebugString())
+		}
+		var s []string
+		var err error
+		if plan != nil {
+			s = plan[i]
+			for _, p := range s {
+				if err = c.Do(p); err != nil {
+					break
+				}
+			}
+			// Rotations have no primitives, they only move the calibs.
+			parts, _ := expandMove(m)
+			for _, p := range parts {
+				if isRotation(p) {
+					c.rotate(p)
+				}
+			}
+		} else {
+			s, err = c.Rotate(m)
 		}
-		s, err := c.Rotate(m)
 		if err != nil {
-			log.Printf("ERROR: Rotate(%s) error: %v", string(m), err)
-			return nil, err
+			return nil, fmt.Errorf("move %d %s: %w", i+1, m, err)
 		}
 		pMoves = append(pMoves, s...)
-		if printStep {
-			fmt.Printf("Step[%d]: newMove=%s %v\n", i+1, m, s)
-			c.Print()
+		if c.opts.Steps != nil {
+			fmt.Fprintf(c.opts.Steps, "Step[%d]: newMove=%s %v\n", i+1, m, s)
+			c.Fprint(c.opts.Steps)
 		}
 	}
 	return pMoves, nil
@@ -408,59 +499,106 @@ func (c *Cube) SetFace(code byte, face *Face) {
 	c.faces[code] = face
 }
 
-func printColor(color Color) {
-	fmt.Printf("%s▇▇\033[0m", colors[color])
+// Face returns the face at the physical position code.
+func (c *Cube) Face(code byte) *Face {
+	return c.faces[code]
+}
+
+// IsSolved reports whether every face of the cube has a single color.
+func (c *Cube) IsSolved() bool {
+	for _, code := range faceCodes {
+		face := c.faces[code]
+		for _, color := range face.Pieces {
+			if color != face.Pieces[4] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Matches reports whether the cube is in the state of t up to a rotation of
+// the whole cube, e.g. whether the robot left it in a target pattern.
+func (c *Cube) Matches(t *Cube) bool {
+	for _, r := range allRoutes()[c.orientation()] {
+		rot := c.Clone()
+		rot.opts = Options{}
+		for _, p := range r.moves {
+			rot.Do(p)
+		}
+		same := true
+		for _, code := range faceCodes {
+			if *rot.faces[code] != *t.faces[code] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
 }
 
-func printPiece(face *Face, row, col int) {
-	printColor(face.Pieces[row*3+col])
+func printColor(w io.Writer, color Color) {
+	fmt.Fprintf(w, "%s▇▇\033[0m", colors[color])
 }
 
-func printRow(face *Face, row int) {
+func printPiece(w io.Writer, face *Face, row, col int) {
+	printColor(w, face.Pieces[row*3+col])
+}
+
+func printRow(w io.Writer, face *Face, row int) {
 	for col := 0; col < 3; col++ {
 		if col == 0 {
-			fmt.Printf("│")
+			fmt.Fprintf(w, "│")
 		} else {
-			fmt.Print(" ")
+			fmt.Fprint(w, " ")
 		}
-		printPiece(face, row, col)
+		printPiece(w, face, row, col)
 	}
 }
 
+// Print prints the cube with ANSI colors to the standard output.
 func (c *Cube) Print() {
+	c.Fprint(os.Stdout)
+}
+
+// Fprint prints the cube with ANSI colors to w.
+func (c *Cube) Fprint(w io.Writer) {
 	const indent = "         "
 
-	fmt.Printf("\t         ┌────────┐\n")
+	fmt.Fprintf(w, "\t         ┌────────┐\n")
 
 	// Print top face.
 	for row := 0; row < 3; row++ {
-		fmt.Printf("\t%s", indent)
-		printRow(c.faces[Up], row)
-		fmt.Printf("│\n")
+		fmt.Fprintf(w, "\t%s", indent)
+		printRow(w, c.faces[Up], row)
+		fmt.Fprintf(w, "│\n")
 	}
 
-	fmt.Printf("\t┌────────┼────────┼────────┬────────┐\n")
+	fmt.Fprintf(w, "\t┌────────┼────────┼────────┬────────┐\n")
 
 	// Print left, front, right and back faces
 	for row := 0; row < 3; row++ {
-		fmt.Printf("\t")
-		printRow(c.faces[Left], row)
-		printRow(c.faces[Front], row)
-		printRow(c.faces[Right], row)
-		printRow(c.faces[Back], row)
-		fmt.Printf("│\n")
+		fmt.Fprintf(w, "\t")
+		printRow(w, c.faces[Left], row)
+		printRow(w, c.faces[Front], row)
+		printRow(w, c.faces[Right], row)
+		printRow(w, c.faces[Back], row)
+		fmt.Fprintf(w, "│\n")
 	}
 
-	fmt.Printf("\t└────────┼────────┼────────┴────────┘\n")
+	fmt.Fprintf(w, "\t└────────┼────────┼────────┴────────┘\n")
 
 	// Print bottom face.
 	for row := 0; row < 3; row++ {
-		fmt.Printf("\t%s", indent)
-		printRow(c.faces[Down], row)
-		fmt.Printf("│\n")
+		fmt.Fprintf(w, "\t%s", indent)
+		printRow(w, c.faces[Down], row)
+		fmt.Fprintf(w, "│\n")
 	}
 
-	fmt.Printf("\t         └────────┘\n")
+	fmt.Fprintf(w, "\t         └────────┘\n")
 }
 
 func parseField(s string) Color {
@@ -468,21 +606,21 @@ func parseField(s string) Color {
 	switch s {
 	case "white", "w":
 		return White
-	case "Red", "r":
+	case "red", "r":
 		return Red
-	case "Green", "g":
+	case "green", "g":
 		return Green
-	case "Blue", "b":
+	case "blue", "b":
 		return Blue
-	case "Yellow", "y":
+	case "yellow", "y":
 		return Yellow
-	case "Orange", "o":
+	case "orange", "o":
 		return Orange
 	}
 	return Unknown
 }
 
-func readFace(faceName, line string) (*Face, error) {
+func readFace(line string) (*Face, error) {
 	face := &Face{}
 	i := 0
 	for _, b := range line {
@@ -496,78 +634,34 @@ func readFace(faceName, line string) (*Face, error) {
 	if i < 9 {
 		return nil, fmt.Errorf("face string must contain 9 chars, but only had %d", 9-i)
 	}
-	log.Printf("readFace %s ok.", faceName)
 	return face, nil
 }
 
-func solve(c *Cube) []string {
-	s := c.KociembaScramble()
-	log.Printf("INFO: exec: %s %s", *kociemba, s)
-	out, err := exec.Command(*kociemba, s).Output()
-	if err != nil {
-		log.Printf("ERROR: failed to run %q: %v", *kociemba, err)
-		os.Exit(255)
-	}
-	return strings.Split(strings.TrimSpace(string(out)), " ")
+// FaceError reports a face of the input which can't be read.
+type FaceError struct {
+	Face string
+	Msg  string
 }
 
-func httpCube(w http.ResponseWriter, req *http.Request) {
-	req.ParseForm()
-	log.Printf("%s: %s %s", req.RemoteAddr, req.Method, req.URL.Path)
+func (e *FaceError) Error() string {
+	return e.Msg
+}
 
+// ReadFaces builds a cube from the six faces returned by get, which is called
+// with the face codes "U", "L", "F", "R", "B" and "D".
+func ReadFaces(get func(string) string) (*Cube, error) {
 	c := NewCube()
-	for _, code := range []byte{Up, Left, Front, Right, Back, Down} {
+	for _, code := range faceCodes {
 		k := fmt.Sprintf("%c", code)
-		v := req.FormValue(k)
+		v := get(k)
 		if len(v) != 9 {
-			log.Printf("ERROR: invalid arg %s=%s", k, v)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(fmt.Sprintf(`face %s must contain only [wrboyg], and must be 9 chars.`, k)))
-			return
+			return nil, &FaceError{k, fmt.Sprintf(`face %s must contain only [wrboyg], and must be 9 chars.`, k)}
 		}
-		face, err := readFace(k, v)
+		face, err := readFace(v)
 		if err != nil {
-			msg := fmt.Sprintf("ERROR: readFace(%s, %s) error: %v", k, v, err)
-			log.Print(msg)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(msg))
-			return
+			return nil, &FaceError{k, fmt.Sprintf("readFace(%s, %s) error: %v", k, v, err)}
 		}
 		c.SetFace(code, face)
 	}
-	fmt.Println("Input cube:")
-	c.Print()
-
-	steps := solve(c)
-	solution := fmt.Sprintf("step=%d: %s", len(steps), strings.Join(steps, " "))
-	log.Printf("INFO: solution: %s\n", solution)
-
-	moves, err := c.Apply(steps, *verbose)
-	if err != nil {
-		fmt.Printf("ERROR: Apply(%v) error: %v", steps, err)
-		return
-	}
-	w.Write([]byte(fmt.Sprintf("OK: %s %v", solution, moves)))
-	if !*verbose {
-		c.Print()
-	}
-
-	log.Printf("SUCCEEDED: move: %v", moves)
-}
-
-func main() {
-	flag.Parse()
-
-	fmt.Println("Please set colors of each pieces on each face.")
-	fmt.Println("Colors are: White Red Green Blue Yellow Orange, or w r g b y o.")
-	fmt.Println("(input 9 whitespace-separated colors for each face):")
-
-	if *debug {
-		*verbose = true
-	}
-
-	http.HandleFunc("/cube", httpCube)
-
-	log.Printf("Starting http server on port %d", *port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), nil))
+	return c, nil
 }
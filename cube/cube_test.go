@@ -0,0 +1,383 @@
+package cube
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ross-wu/cube/twophase"
+)
+
+// scrambleMoves are the moves drawn for random scrambles, all of the WCA
+// notation of wca.go.
+var scrambleMoves = []string{
+	"U", "L", "F", "R", "B", "D",
+	"M", "E", "S",
+	"Uw", "Lw", "Fw", "Rw", "Bw", "Dw", "r",
+	"x", "y", "z",
+}
+
+func randomScramble(r *rand.Rand, n int) []string {
+	moves := make([]string, n)
+	for i := range moves {
+		moves[i] = scrambleMoves[r.Intn(len(scrambleMoves))] + []string{"", "'", "2"}[r.Intn(3)]
+	}
+	return moves
+}
+
+// sameState reports whether a and b have the same faces at the same positions
+// and the same calibs.
+func sameState(a, b *Cube) bool {
+	for _, code := range faceCodes {
+		if *a.faces[code] != *b.faces[code] || a.calibs[code] != b.calibs[code] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestIdentities(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tc := range []struct {
+		name string
+		do   func(c *Cube)
+	}{
+		{"D*4", func(c *Cube) { c.D().D().D().D() }},
+		{"D2*2", func(c *Cube) { c.D2().D2() }},
+		{"D D'", func(c *Cube) { c.D().d() }},
+		{"flip*4", func(c *Cube) { c.flip().flip().flip().flip() }},
+		{"turn(4)", func(c *Cube) { c.turn(4) }},
+		{"turn turn'", func(c *Cube) { c.turn(1).reverseTurn() }},
+	} {
+		for i := 0; i < 20; i++ {
+			c := RandomCube(r)
+			want := c.Clone()
+			tc.do(c)
+			if !sameState(c, want) {
+				t.Fatalf("%s isn't the identity on %s", tc.name, c.formatULFRBD())
+			}
+		}
+	}
+}
+
+func TestIsSolved(t *testing.T) {
+	c := SolvedCube()
+	if !c.IsSolved() {
+		t.Fatalf("IsSolved() of the solved cube = false")
+	}
+	c.flip().turn(1)
+	if !c.IsSolved() {
+		t.Errorf("IsSolved() of the solved cube after flip turn = false")
+	}
+	c.D()
+	if c.IsSolved() {
+		t.Errorf("IsSolved() after D = true")
+	}
+}
+
+// TestScrambleSolution checks that the robot moves of the inverse of random
+// scrambles solve the scrambled cubes with both planners.
+func TestScrambleSolution(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, planner := range Planners {
+		for i := 0; i < 50; i++ {
+			scramble := randomScramble(r, 1+r.Intn(25))
+			c, err := New(Options{Planner: planner})
+			if err != nil {
+				t.Fatalf("New(%s) error: %v", planner, err)
+			}
+			c.faces = SolvedCube().faces
+			if _, err := c.Scramble(strings.Join(scramble, " ")); err != nil {
+				t.Fatalf("Scramble(%v) error: %v", scramble, err)
+			}
+			solution := InvertMoves(scramble)
+			if _, err := c.Apply(solution); err != nil {
+				t.Fatalf("Apply(%v) error: %v", solution, err)
+			}
+			if !c.IsSolved() {
+				t.Errorf("%s planner: the moves of %v don't solve the scramble %v: %s",
+					planner, solution, scramble, c.formatULFRBD())
+			}
+		}
+	}
+}
+
+// TestMatches checks that the robot moves of the inverse of random scrambles
+// take the scrambled cubes back to random states, which Matches up to the
+// orientation the robot leaves the cube in.
+func TestMatches(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 50; i++ {
+		want := RandomCube(r)
+		c := want.Clone()
+		scramble := randomScramble(r, 1+r.Intn(25))
+		if _, err := c.Scramble(strings.Join(scramble, " ")); err != nil {
+			t.Fatalf("Scramble(%v) error: %v", scramble, err)
+		}
+		if _, err := c.Apply(InvertMoves(scramble)); err != nil {
+			t.Fatalf("Apply(%v) error: %v", InvertMoves(scramble), err)
+		}
+		if !c.Matches(want) {
+			t.Errorf("the inverse of %v doesn't return to %s: %s", scramble, want.formatULFRBD(), c.formatULFRBD())
+		}
+	}
+
+	c := SolvedCube()
+	c.flip().turn(1)
+	if !c.Matches(SolvedCube()) {
+		t.Errorf("the rotated solved cube doesn't match the solved cube")
+	}
+	c.D()
+	if c.Matches(SolvedCube()) {
+		t.Errorf("the cube after D matches the solved cube")
+	}
+}
+
+func TestParsePlacements(t *testing.T) {
+	all := Placements()
+	// FR, then the other placements in their order.
+	frAll := []string{"FR"}
+	for _, name := range all {
+		if name != "FR" {
+			frAll = append(frAll, name)
+		}
+	}
+	for _, tc := range []struct {
+		s    string
+		want []string
+		err  bool
+	}{
+		{s: "UF", want: []string{"UF"}},
+		{s: "uf, FR ,ru", want: []string{"UF", "FR", "RU"}},
+		{s: "UF,UF", want: []string{"UF"}},
+		{s: "all", want: all},
+		{s: "ALL", want: all},
+		{s: "FR,all", want: frAll},
+		{s: "all,UF", want: all},
+		{s: "", err: true},
+		{s: "UF,", err: true},
+		{s: "UU", err: true},
+		{s: "all,XY", err: true},
+	} {
+		got, err := ParsePlacements(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("ParsePlacements(%q) = %v, want an error", tc.s, got)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("ParsePlacements(%q) = %v, %v, want %v", tc.s, got, err, tc.want)
+		}
+	}
+	if len(all) != 24 || all[0] != "UF" {
+		t.Errorf("Placements() = %v, want 24 placements, UF first", all)
+	}
+}
+
+// TestValidate checks each diagnostic of Validate on the solved cube with some
+// facelets changed, indexed in its facelet string.
+func TestValidate(t *testing.T) {
+	const solved = "UUUUUUUUURRRRRRRRRFFFFFFFFFDDDDDDDDDLLLLLLLLLBBBBBBBBB"
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 20; i++ {
+		if err := RandomCube(r).Validate(); err != nil {
+			t.Fatalf("Validate() of a random cube error: %v", err)
+		}
+	}
+	for _, tc := range []struct {
+		name    string
+		changes map[int]byte
+		code    string
+		msg     string
+	}{
+		{"solved", nil, "", ""},
+		{"color count", map[int]byte{0: 'R'}, ErrCodeColors, "10 red"},
+		{"centers", map[int]byte{4: 'R', 9: 'U'}, ErrCodeCenters, "both red"},
+		{"no such corner", map[int]byte{20: 'L', 38: 'F'}, ErrCodeCorners, "the corner at URF has colors white/red/orange"},
+		// UFL is a second URF.
+		{"duplicated corner", map[int]byte{18: 'R', 38: 'F', 10: 'L'}, ErrCodeCorners, "the corner URF is at both URF and UFL, but corner UFL is missing"},
+		{"no such edge", map[int]byte{19: 'R', 12: 'F'}, ErrCodeEdges, "the edge at FR has colors green/green"},
+		// UF is a second UR, and DR a second DF.
+		{"duplicated edge", map[int]byte{19: 'R', 16: 'F'}, ErrCodeEdges, "the edge UR is at both UR and UF, but edge UF and DR is missing"},
+		{"twist", map[int]byte{8: 'F', 9: 'U', 20: 'R'}, ErrCodeTwist, "the corner at URF must be twisted counterclockwise"},
+		{"twist clockwise", map[int]byte{8: 'R', 9: 'F', 20: 'U'}, ErrCodeTwist, "the corner at URF must be twisted clockwise"},
+		{"flip", map[int]byte{7: 'F', 19: 'U'}, ErrCodeFlip, "the edge at UF must be flipped"},
+		{"edge parity", map[int]byte{10: 'F', 19: 'R'}, ErrCodeParity, "edges UR and UF must be swapped"},
+		{"corner parity", map[int]byte{9: 'F', 20: 'L', 18: 'R', 38: 'F'}, ErrCodeParity, "corners URF and UFL must be swapped"},
+	} {
+		facelets := []byte(solved)
+		for i, code := range tc.changes {
+			facelets[i] = code
+		}
+		c, err := ParseCube(FormatFacelets, string(facelets))
+		if err != nil {
+			t.Fatalf("%s: ParseCube(%s) error: %v", tc.name, facelets, err)
+		}
+		err = c.Validate()
+		if tc.code == "" {
+			if err != nil {
+				t.Errorf("%s: Validate() error: %v", tc.name, err)
+			}
+			continue
+		}
+		e, ok := err.(*ValidationError)
+		if !ok || e.Code != tc.code || !strings.Contains(e.Msg, tc.msg) {
+			t.Errorf("%s: Validate() of %s error: %v, want %s: %q", tc.name, facelets, err, tc.code, tc.msg)
+		}
+	}
+}
+
+// doMoves does the physical moves on a copy of the cube.
+func doMoves(t *testing.T, c *Cube, moves []string) *Cube {
+	t.Helper()
+	c = c.Clone()
+	for _, m := range moves {
+		if err := c.Do(m); err != nil {
+			t.Fatalf("Do(%s) error: %v", m, err)
+		}
+	}
+	return c
+}
+
+// TestOptimize checks that the optimized moves of random scrambles, planned by
+// both planners, and of random primitives leave random cubes in the same state
+// as the moves themselves, and are never longer.
+func TestOptimize(t *testing.T) {
+	primitives := []string{MoveFlip, MoveTurn1, MoveTurn2, MoveRTurn, MoveD, MoveD2, Moved}
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 300; i++ {
+		start := RandomCube(r)
+		var moves []string
+		if planner := i % (len(Planners) + 1); planner < len(Planners) {
+			c, err := New(Options{Planner: Planners[planner]})
+			if err != nil {
+				t.Fatalf("New(%s) error: %v", Planners[planner], err)
+			}
+			c.faces = start.Clone().faces
+			scramble := randomScramble(r, 1+r.Intn(25))
+			if moves, err = c.Apply(scramble); err != nil {
+				t.Fatalf("Apply(%v) error: %v", scramble, err)
+			}
+		} else {
+			for n := r.Intn(40); n > 0; n-- {
+				moves = append(moves, primitives[r.Intn(len(primitives))])
+			}
+		}
+
+		opt := Optimize(moves)
+		if len(opt) > len(moves) {
+			t.Errorf("Optimize(%v) = %v, longer", moves, opt)
+		}
+		if want, got := doMoves(t, start, moves), doMoves(t, start, opt); !sameState(got, want) {
+			t.Errorf("Optimize(%v) = %v, leaves %s instead of %s", moves, opt, got.formatULFRBD(), want.formatULFRBD())
+		}
+		if again := Optimize(opt); len(again) != len(opt) {
+			t.Errorf("Optimize(%v) = %v, optimized again %v", moves, opt, again)
+		}
+	}
+}
+
+// TestWCAMoves checks the slice and wide moves and the rotations on the solved
+// cube, against its stickers or against the same move written with other moves
+// as in the WCA regulations.
+func TestWCAMoves(t *testing.T) {
+	for _, tc := range []struct {
+		move string
+		// want is the cube after the move in FormatULFRBD, or same are
+		// moves which do the same.
+		want, same string
+	}{
+		{move: "M", want: "wbwwbwwbw ooooooooo gwggwggwg rrrrrrrrr bybbybbyb ygyygyygy"},
+		{move: "M'", same: "M M M"},
+		{move: "M2", same: "M M"},
+		{move: "E", want: "wwwwwwwww ooobbbooo gggoooggg rrrgggrrr bbbrrrbbb yyyyyyyyy"},
+		{move: "E'", same: "E E E"},
+		{move: "E2", same: "E E"},
+		{move: "S", want: "wwwooowww oyooyooyo ggggggggg rwrrwrrwr bbbbbbbbb yyyrrryyy"},
+		{move: "S'", same: "S S S"},
+		{move: "S2", same: "S S"},
+		{move: "x", want: "ggggggggg ooooooooo yyyyyyyyy rrrrrrrrr wwwwwwwww bbbbbbbbb"},
+		{move: "x'", same: "x x x"},
+		{move: "x2", same: "x x"},
+		{move: "y", want: "wwwwwwwww ggggggggg rrrrrrrrr bbbbbbbbb ooooooooo yyyyyyyyy"},
+		{move: "y'", same: "y y y"},
+		{move: "y2", same: "y y"},
+		{move: "z", want: "ooooooooo yyyyyyyyy ggggggggg wwwwwwwww bbbbbbbbb rrrrrrrrr"},
+		{move: "z'", same: "z z z"},
+		{move: "z2", same: "z z"},
+		{move: "x", same: "R M' L'"},
+		{move: "y", same: "U E' D'"},
+		{move: "z", same: "F S B'"},
+		{move: "Rw", same: "R M'"},
+		{move: "Lw", same: "L M"},
+		{move: "Uw", same: "U E'"},
+		{move: "Dw", same: "D E"},
+		{move: "Fw", same: "F S"},
+		{move: "Bw", same: "B S'"},
+		{move: "Rw'", same: "R' M"},
+		{move: "Rw2", same: "R2 M2"},
+		{move: "Fw'", same: "F' S'"},
+		{move: "Dw2", same: "D2 E2"},
+		{move: "r", same: "Rw"},
+		{move: "l", same: "Lw"},
+		{move: "u", same: "Uw"},
+		{move: "d", same: "Dw"},
+		{move: "f", same: "Fw"},
+		{move: "b", same: "Bw"},
+		{move: "r'", same: "Rw'"},
+		{move: "u2", same: "Uw2"},
+		{move: "b'", same: "Bw'"},
+	} {
+		c := SolvedCube()
+		if _, err := c.Scramble(tc.move); err != nil {
+			t.Fatalf("Scramble(%s) error: %v", tc.move, err)
+		}
+		if tc.want != "" {
+			if got := c.formatULFRBD(); got != tc.want {
+				t.Errorf("%s on the solved cube = %s, want %s", tc.move, got, tc.want)
+			}
+			continue
+		}
+		want := SolvedCube()
+		if _, err := want.Scramble(tc.same); err != nil {
+			t.Fatalf("Scramble(%s) error: %v", tc.same, err)
+		}
+		if !sameState(c, want) {
+			t.Errorf("%s on the solved cube = %s, want the one of %s: %s", tc.move, c.formatULFRBD(), tc.same, want.formatULFRBD())
+		}
+	}
+}
+
+// TestPatternize checks that the solution of the patternized cube takes random
+// cubes to each preset pattern, and to a random cube.
+func TestPatternize(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	for _, name := range append(PatternNames[:len(PatternNames):len(PatternNames)], "random") {
+		for i := 0; i < 3; i++ {
+			c := RandomCube(r)
+			p := RandomCube(r)
+			if name != "random" {
+				var err error
+				if p, err = PatternCube(name, c); err != nil {
+					t.Fatalf("PatternCube(%s) error: %v", name, err)
+				}
+			}
+			s, err := Patternize(c.KociembaScramble(), p.KociembaScramble())
+			if err != nil {
+				t.Fatalf("%s: Patternize(%s, %s) error: %v", name, c.KociembaScramble(), p.KociembaScramble(), err)
+			}
+			solution, err := twophase.Solution(s, 24, time.Minute, false, "../cache")
+			if err != nil {
+				t.Fatalf("%s: Solution(%s) error: %v", name, s, err)
+			}
+			if _, err := c.Scramble(solution); err != nil {
+				t.Fatalf("Scramble(%s) error: %v", solution, err)
+			}
+			if got, want := c.formatULFRBD(), p.formatULFRBD(); got != want {
+				t.Errorf("%s: the solution %s of %s leaves %s, want %s", name, solution, s, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Conversions between the Cube and the common text formats of a cube state.
+//
+// Supported formats:
+//
+//   ulfrbd:   "wwwwwwwww ooooooooo ggggggggg rrrrrrrrr bbbbbbbbb yyyyyyyyy",
+//             six faces of 9 color letters in the order Up Left Front Right
+//             Back Down. This is the --input format of lego_cube.
+//   facelets: "UUUUUUUUURRRRRRRRRFFFFFFFFFDDDDDDDDDLLLLLLLLLBBBBBBBBB", the
+//             54-facelet string of Kociemba's solver, faces in the order Up
+//             Right Front Down Left Back.
+//   rows:     one row per face of 9 whitespace-separated color letters, faces
+//             in the order Up Left Front Right Back Down, like in.1.
+//   names:    54 color names separated by whitespace or commas, faces in the
+//             order Up Left Front Right Back Down.
+//
+// The stickers of each face are listed row by row, with the faces laid out
+// as in Cube.Print.
+//
+package cube
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatULFRBD   Format = "ulfrbd"
+	FormatFacelets Format = "facelets"
+	FormatRows     Format = "rows"
+	FormatNames    Format = "names"
+)
+
+var (
+	Formats = []Format{FormatULFRBD, FormatFacelets, FormatRows, FormatNames}
+
+	// kociembaCodes is the face order of the facelet string.
+	kociembaCodes = []byte{Up, Right, Front, Down, Left, Back}
+
+	// faceColors is the color scheme used for a facelet string, which has no
+	// colors: white up, green front.
+	faceColors = map[byte]Color{
+		Up:    White,
+		Left:  Orange,
+		Front: Green,
+		Right: Red,
+		Back:  Blue,
+		Down:  Yellow,
+	}
+	colorLetters = map[Color]byte{
+		White:  'w',
+		Red:    'r',
+		Green:  'g',
+		Blue:   'b',
+		Yellow: 'y',
+		Orange: 'o',
+	}
+	colorNames = map[Color]string{
+		White:  "white",
+		Red:    "red",
+		Green:  "green",
+		Blue:   "blue",
+		Yellow: "yellow",
+		Orange: "orange",
+	}
+)
+
+// DetectFormat guesses the format of a cube state.
+func DetectFormat(s string) (Format, error) {
+	fields := splitFields(s)
+	switch {
+	case len(fields) == 1 && len(fields[0]) == 54:
+		return FormatFacelets, nil
+	case len(fields) == 6:
+		return FormatULFRBD, nil
+	case len(fields) == 54 && len(fields[0]) == 1:
+		return FormatRows, nil
+	case len(fields) == 54:
+		return FormatNames, nil
+	}
+	return "", fmt.Errorf("unknown cube state format: %q", s)
+}
+
+// ParseCube parses a cube state in the given format. An empty format is
+// detected by DetectFormat.
+func ParseCube(format Format, s string) (*Cube, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectFormat(s); err != nil {
+			return nil, err
+		}
+	}
+	switch format {
+	case FormatULFRBD:
+		return parseULFRBD(s)
+	case FormatFacelets:
+		return parseFacelets(s)
+	case FormatRows, FormatNames:
+		return parseColorList(s)
+	}
+	return nil, fmt.Errorf("unknown format %q, must be one of %v", format, Formats)
+}
+
+// Format formats the cube state in the given format.
+func (c *Cube) Format(format Format) (string, error) {
+	switch format {
+	case FormatULFRBD:
+		return c.formatULFRBD(), nil
+	case FormatFacelets:
+		return c.Facelets()
+	case FormatRows:
+		return c.formatColorList("\n", func(color Color) string {
+			return string(colorLetters[color])
+		}), nil
+	case FormatNames:
+		return c.formatColorList(" ", func(color Color) string {
+			return colorNames[color]
+		}), nil
+	}
+	return "", fmt.Errorf("unknown format %q, must be one of %v", format, Formats)
+}
+
+// Faces returns the 9 color letters of each face keyed by the face code, which
+// is the input of /cube.
+func (c *Cube) Faces() map[string]string {
+	faces := map[string]string{}
+	for _, code := range faceCodes {
+		faces[string(code)] = c.faceLetters(code)
+	}
+	return faces
+}
+
+// Facelets returns the 54-facelet string of the cube. The faces are named by
+// the colors of their centers.
+func (c *Cube) Facelets() (string, error) {
+	colorToCode := map[Color]byte{}
+	for _, code := range faceCodes {
+		colorToCode[c.faces[code].Pieces[4]] = code
+	}
+	if len(colorToCode) != 6 {
+		return "", fmt.Errorf("the 6 centers must have different colors")
+	}
+	buf := make([]byte, 0, 54)
+	for _, code := range kociembaCodes {
+		for _, color := range c.faces[code].Pieces {
+			b, ok := colorToCode[color]
+			if !ok {
+				return "", fmt.Errorf("no center has the color %s", colorNames[color])
+			}
+			buf = append(buf, b)
+		}
+	}
+	return string(buf), nil
+}
+
+func (c *Cube) faceLetters(code byte) string {
+	buf := make([]byte, 9)
+	for i, color := range c.faces[code].Pieces {
+		buf[i] = colorLetters[color]
+	}
+	return string(buf)
+}
+
+func (c *Cube) formatULFRBD() string {
+	s := make([]string, 0, 6)
+	for _, code := range faceCodes {
+		s = append(s, c.faceLetters(code))
+	}
+	return strings.Join(s, " ")
+}
+
+func (c *Cube) formatColorList(sep string, name func(Color) string) string {
+	var buf bytes.Buffer
+	for i, code := range faceCodes {
+		if i > 0 {
+			buf.WriteString(sep)
+		}
+		for j, color := range c.faces[code].Pieces {
+			if j > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(name(color))
+		}
+	}
+	return buf.String()
+}
+
+func splitFields(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+func parseULFRBD(s string) (*Cube, error) {
+	fields := splitFields(s)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("cube state must contain 6 faces, but had %d", len(fields))
+	}
+	c := NewCube()
+	for i, code := range faceCodes {
+		if len(fields[i]) != 9 {
+			return nil, &FaceError{string(code), fmt.Sprintf(`face %c must contain only [wrboyg], and must be 9 chars.`, code)}
+		}
+		face, err := readFace(fields[i])
+		if err != nil {
+			return nil, &FaceError{string(code), err.Error()}
+		}
+		c.SetFace(code, face)
+	}
+	return c, nil
+}
+
+func parseFacelets(s string) (*Cube, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 54 {
+		return nil, fmt.Errorf("facelet string must contain 54 chars, but had %d", len(s))
+	}
+	c := NewCube()
+	for i, code := range kociembaCodes {
+		face := &Face{}
+		for j := range face.Pieces {
+			b := s[i*9+j]
+			color, ok := faceColors[b]
+			if !ok {
+				return nil, &FaceError{string(code), fmt.Sprintf("unknown face name: %c", b)}
+			}
+			face.Pieces[j] = color
+		}
+		c.SetFace(code, face)
+	}
+	return c, nil
+}
+
+func parseColorList(s string) (*Cube, error) {
+	fields := splitFields(s)
+	if len(fields) != 54 {
+		return nil, fmt.Errorf("cube state must contain 54 colors, but had %d", len(fields))
+	}
+	c := NewCube()
+	for i, code := range faceCodes {
+		face := &Face{}
+		for j := range face.Pieces {
+			name := fields[i*9+j]
+			face.Pieces[j] = parseField(name)
+			if face.Pieces[j] == Unknown {
+				return nil, &FaceError{string(code), fmt.Sprintf("unknown color name: %s", name)}
+			}
+		}
+		c.SetFace(code, face)
+	}
+	return c, nil
+}
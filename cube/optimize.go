@@ -0,0 +1,111 @@
+// Peephole optimizer of the physical moves.
+//
+// turn and D both rotate around the vertical axis, so between two flips the
+// turns and the D moves commute and add up: "turn D turn'" is just "D", and
+// "turn turn2" is "turn'". Four flips in a row do nothing. The optimizer
+// merges them until nothing changes, and VerifyMoves proves that the result
+// leaves the cube in the same state.
+//
+package cube
+
+import "fmt"
+
+var (
+	// dCount is the number of clockwise quarter turns of each D primitive.
+	dCount = map[string]int{
+		MoveD:  1,
+		MoveD2: 2,
+		Moved:  3,
+	}
+	quarterTurns = [4]string{"", MoveTurn1, MoveTurn2, MoveRTurn}
+	quarterDs    = [4]string{"", MoveD, MoveD2, Moved}
+)
+
+// Optimize rewrites the physical moves into an equivalent sequence which is
+// as short or shorter. Unknown moves are kept as they are, and nothing is
+// moved across them.
+func Optimize(moves []string) []string {
+	for {
+		out := optimizePass(moves)
+		if len(out) == len(moves) {
+			return out
+		}
+		moves = out
+	}
+}
+
+func optimizePass(moves []string) []string {
+	out := []string{}
+	for i := 0; i < len(moves); {
+		switch {
+		case moves[i] == MoveFlip:
+			n := 0
+			for ; i < len(moves) && moves[i] == MoveFlip; i++ {
+				n++
+			}
+			for n %= 4; n > 0; n-- {
+				out = append(out, MoveFlip)
+			}
+		case turnCount[moves[i]] > 0 || dCount[moves[i]] > 0:
+			turns, ds := 0, 0
+			for ; i < len(moves); i++ {
+				if n, ok := turnCount[moves[i]]; ok {
+					turns += n
+				} else if n, ok := dCount[moves[i]]; ok {
+					ds += n
+				} else {
+					break
+				}
+			}
+			if ds%4 != 0 {
+				out = append(out, quarterDs[ds%4])
+			}
+			if turns%4 != 0 {
+				out = append(out, quarterTurns[turns%4])
+			}
+		default:
+			out = append(out, moves[i])
+			i++
+		}
+	}
+	return out
+}
+
+// Clone returns a deep copy of the cube.
+func (c *Cube) Clone() *Cube {
+	n := NewCube()
+	for code, face := range c.faces {
+		f := *face
+		n.faces[code] = &f
+	}
+	for code, pos := range c.calibs {
+		n.calibs[code] = pos
+	}
+	n.opts = c.opts
+	return n
+}
+
+// VerifyMoves replays both physical move sequences on copies of the cube and
+// returns an error if the cubes end up in different states.
+func (c *Cube) VerifyMoves(a, b []string) error {
+	ca, cb := c.Clone(), c.Clone()
+	for _, m := range a {
+		if err := ca.Do(m); err != nil {
+			return err
+		}
+	}
+	for _, m := range b {
+		if err := cb.Do(m); err != nil {
+			return err
+		}
+	}
+	for _, code := range faceCodes {
+		if *ca.faces[code] != *cb.faces[code] {
+			return fmt.Errorf("the %s faces differ: %s and %s", FaceName(code), ca.faceLetters(code), cb.faceLetters(code))
+		}
+		if ca.calibs[code] != cb.calibs[code] {
+			return fmt.Errorf("face %c is at %c and %c", code, ca.calibs[code], cb.calibs[code])
+		}
+	}
+	return nil
+}
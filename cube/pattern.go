@@ -0,0 +1,103 @@
+// Target patterns: solve the cube into a pattern instead of the solved cube.
+//
+// Like Kociemba's patternize, the cube is solved as pattern^-1 * cube on the
+// piece level, so the solution takes the cube to the pattern with any of the
+// solvers. The target must have the same centers as the cube.
+//
+package cube
+
+import "fmt"
+
+// Patterns are the preset targets, each made by its moves from the solved
+// cube.
+var Patterns = map[string]string{
+	"solved":       "",
+	"checkerboard": "U2 D2 F2 B2 L2 R2",
+	"superflip":    "U R2 F B R B2 R U2 L B2 R U' D' R2 F R' L B2 U2 F2",
+	"cube-in-cube": "F L F U' R U F2 L2 U' L' B D' B' L2 U",
+	"six-spots":    "U D' R L' F B' U D'",
+}
+
+// PatternNames lists the names of Patterns.
+var PatternNames = []string{"solved", "checkerboard", "superflip", "cube-in-cube", "six-spots"}
+
+// PatternCube returns the preset pattern in the colors of the centers of c.
+func PatternCube(name string, c *Cube) (*Cube, error) {
+	moves, ok := Patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern %q, must be one of %v", name, PatternNames)
+	}
+	p := SolvedCube()
+	if _, err := p.Scramble(moves); err != nil {
+		return nil, err
+	}
+	colorOf := map[Color]Color{}
+	for _, code := range faceCodes {
+		colorOf[faceColors[code]] = c.faces[code].Pieces[4]
+	}
+	for _, face := range p.faces {
+		for i, color := range face.Pieces {
+			face.Pieces[i] = colorOf[color]
+		}
+	}
+	return p, nil
+}
+
+// Patternize returns the facelets of the cube whose solution takes the cube of
+// facelets to the cube of pattern, like patternize of Kociemba's solver.
+func Patternize(facelets, pattern string) (string, error) {
+	cc, err := faceletCubies(facelets)
+	if err != nil {
+		return "", err
+	}
+	pc, err := faceletCubies(pattern)
+	if err != nil {
+		return "", err
+	}
+	return pc.inverse().multiply(cc).cube().Facelets()
+}
+
+func faceletCubies(s string) (*cubies, error) {
+	c, err := parseFacelets(s)
+	if err != nil {
+		return nil, err
+	}
+	codeOf := map[Color]byte{}
+	for code, color := range faceColors {
+		codeOf[color] = code
+	}
+	return c.cubies(codeOf)
+}
+
+// multiply returns the pieces of cc followed by b, like multiply of
+// Kociemba's solver.
+func (cc *cubies) multiply(b *cubies) *cubies {
+	m := &cubies{}
+	for i := range m.cp {
+		m.cp[i] = cc.cp[b.cp[i]]
+		m.co[i] = (cc.co[b.cp[i]] + b.co[i]) % 3
+	}
+	for i := range m.ep {
+		m.ep[i] = cc.ep[b.ep[i]]
+		m.eo[i] = (cc.eo[b.ep[i]] + b.eo[i]) % 2
+	}
+	return m
+}
+
+// inverse returns the pieces which undo cc.
+func (cc *cubies) inverse() *cubies {
+	inv := &cubies{}
+	for i, j := range cc.cp {
+		inv.cp[j] = i
+	}
+	for i := range inv.co {
+		inv.co[i] = (3 - cc.co[inv.cp[i]]) % 3
+	}
+	for i, j := range cc.ep {
+		inv.ep[j] = i
+	}
+	for i := range inv.eo {
+		inv.eo[i] = cc.eo[inv.ep[i]]
+	}
+	return inv
+}
@@ -0,0 +1,98 @@
+// Placements of the cube in the robot.
+//
+// The solution depends on how the cube happens to be placed in the robot.
+// Relabelling the faces as if the whole cube were rotated gives another
+// solution, which may need far fewer flips. A placement is named by the faces
+// which would be at the top and the front, e.g. "UF" is the cube as it is, and
+// "FR" has the front face at the top and the right face at the front.
+//
+package cube
+
+import (
+	"fmt"
+	"strings"
+)
+
+func placementName(o orientation) string {
+	var up, front byte
+	for i, pos := range o {
+		switch pos {
+		case Up:
+			up = faceCodes[i]
+		case Front:
+			front = faceCodes[i]
+		}
+	}
+	return string([]byte{up, front})
+}
+
+// Placements returns the names of the 24 placements, "UF" first.
+func Placements() []string {
+	allRoutes()
+	names := make([]string, len(orientations))
+	for i, o := range orientations {
+		names[i] = placementName(o)
+	}
+	return names
+}
+
+// ParsePlacements parses a comma-separated list of placement names, where
+// "all" stands for the 24 placements. A placement listed twice is kept once.
+func ParsePlacements(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		list := []string{name}
+		if name == "ALL" {
+			list = Placements()
+		} else if indexOf(Placements(), name) < 0 {
+			return nil, fmt.Errorf("unknown placement %q, must be all or some of %v", name, Placements())
+		}
+		for _, name := range list {
+			if indexOf(names, name) < 0 {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func placementOrientation(name string) (orientation, error) {
+	allRoutes()
+	for _, o := range orientations {
+		if placementName(o) == name {
+			return o, nil
+		}
+	}
+	return orientation{}, fmt.Errorf("unknown placement %q, must be one of %v", name, Placements())
+}
+
+// Place returns a copy of the cube rotated to the named placement, with
+// calibs reset so that its KociembaScramble is in the rotated frame. The cube
+// must not have been moved yet.
+func (c *Cube) Place(name string) (*Cube, error) {
+	o, err := placementOrientation(name)
+	if err != nil {
+		return nil, err
+	}
+	rot := c.Clone()
+	for _, p := range allRoutes()[NewCube().orientation()][o].moves {
+		rot.Do(p)
+	}
+	rot.calibs = NewCube().calibs
+	return rot, nil
+}
+
+// UsePlacement sets the calibs of the cube to map the faces of the named
+// placement to the physical faces, so that a solution of the cube in the
+// placement applies to the cube. The cube must not have been moved yet.
+func (c *Cube) UsePlacement(name string) error {
+	o, err := placementOrientation(name)
+	if err != nil {
+		return err
+	}
+	for i, pos := range o {
+		c.calibs[pos] = faceCodes[i]
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+// Planner of the physical moves of the robot.
+//
+// The robot only turns the down face, so each face move of a solution first
+// brings the face to the bottom with flip and turn primitives. The static
+// planner uses one fixed sequence per face, see newMoves. The search planner
+// tracks the orientation of the whole cube through the solution and picks the
+// primitives with the lowest estimated robot time, e.g. for "R U" it may end R
+// in an orientation where U is one flip away instead of two.
+//
+package cube
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Planner string
+
+const (
+	PlannerStatic Planner = "static"
+	PlannerSearch Planner = "search"
+)
+
+var Planners = []Planner{PlannerSearch, PlannerStatic}
+
+// primitiveTimes estimates the robot time of each primitive with the default
+// speeds of lego_cube, 300 tacho counts per second: a flip moves the flip motor
+// 220 counts out and back with a 200ms pause, a quarter turn is 270 counts, and
+// a D move holds the cube (105 counts), turns and releases it.
+var primitiveTimes = map[string]time.Duration{
+	MoveFlip:  1650 * time.Millisecond,
+	MoveTurn1: 900 * time.Millisecond,
+	MoveRTurn: 900 * time.Millisecond,
+	MoveTurn2: 1800 * time.Millisecond,
+	MoveD:     1600 * time.Millisecond,
+	Moved:     1600 * time.Millisecond,
+	MoveD2:    2500 * time.Millisecond,
+}
+
+// turnCount is the number of quarter turns of each turn primitive.
+var turnCount = map[string]int{
+	MoveTurn1: 1,
+	MoveTurn2: 2,
+	MoveRTurn: 3,
+}
+
+// dMoves maps the suffix of a face move to the D primitive.
+var dMoves = map[string]string{
+	"":  MoveD,
+	"2": MoveD2,
+	"'": Moved,
+}
+
+// EstimateTime returns the estimated robot time of the primitives.
+func EstimateTime(moves []string) time.Duration {
+	var d time.Duration
+	for _, m := range moves {
+		d += primitiveTimes[m]
+	}
+	return d
+}
+
+// orientation is the physical position of each face, in the order of
+// faceCodes. It's the state of calibs without the faces.
+type orientation [6]byte
+
+func (c *Cube) orientation() orientation {
+	var o orientation
+	for i, code := range faceCodes {
+		o[i] = c.calibs[code]
+	}
+	return o
+}
+
+// apply returns the orientation after a flip or turn primitive.
+func (o orientation) apply(p string) orientation {
+	codes, n := flipCodes, 1
+	if p != MoveFlip {
+		codes, n = turnCodes, turnCount[p]
+	}
+	for ; n > 0; n-- {
+		for i, pos := range o {
+			if to, ok := codes[pos]; ok {
+				o[i] = to
+			}
+		}
+	}
+	return o
+}
+
+// position returns the physical position of the face code.
+func (o orientation) position(code byte) byte {
+	for i, c := range faceCodes {
+		if c == code {
+			return o[i]
+		}
+	}
+	return 0
+}
+
+// route is the cheapest primitive sequence between two orientations.
+type route struct {
+	moves []string
+	time  time.Duration
+}
+
+// shorter reports whether r is cheaper than s, or as cheap with fewer
+// primitives.
+func (r route) shorter(s route) bool {
+	return r.time < s.time || r.time == s.time && len(r.moves) < len(s.moves)
+}
+
+var (
+	routesOnce sync.Once
+	// orientations lists the 24 orientations in a fixed order, so that
+	// plans are deterministic.
+	orientations []orientation
+	// routes maps every pair of orientations to their cheapest route.
+	routes map[orientation]map[orientation]route
+)
+
+// allRoutes computes the cheapest routes from each orientation to all the
+// others with Dijkstra's algorithm.
+func allRoutes() map[orientation]map[orientation]route {
+	routesOnce.Do(func() {
+		seen := map[orientation]bool{}
+		todo := []orientation{NewCube().orientation()}
+		for len(todo) > 0 {
+			o := todo[0]
+			todo = todo[1:]
+			if seen[o] {
+				continue
+			}
+			seen[o] = true
+			orientations = append(orientations, o)
+			for _, p := range []string{MoveFlip, MoveTurn1} {
+				todo = append(todo, o.apply(p))
+			}
+		}
+		routes = map[orientation]map[orientation]route{}
+		for _, o := range orientations {
+			routes[o] = routesFrom(o)
+		}
+	})
+	return routes
+}
+
+func routesFrom(from orientation) map[orientation]route {
+	best := map[orientation]route{from: {}}
+	done := map[orientation]bool{}
+	for {
+		var cur orientation
+		found := false
+		for _, o := range orientations {
+			r, ok := best[o]
+			if ok && !done[o] && (!found || r.shorter(best[cur])) {
+				cur, found = o, true
+			}
+		}
+		if !found {
+			return best
+		}
+		done[cur] = true
+		for _, p := range []string{MoveFlip, MoveTurn1, MoveRTurn, MoveTurn2} {
+			next := cur.apply(p)
+			moves := append(append([]string{}, best[cur].moves...), p)
+			r := route{moves, best[cur].time + primitiveTimes[p]}
+			if old, ok := best[next]; !ok || r.shorter(old) {
+				best[next] = r
+			}
+		}
+	}
+}
+
+// plan returns the primitives of each move with the search planner. It finds
+// the cheapest sequence of orientations, one with the face of each move at
+// the bottom, by dynamic programming over the 24 orientations. Slice and wide
+// moves are planned as their face moves, and rotations cost nothing.
+func (c *Cube) plan(moves []Move) ([][]string, error) {
+	routes := allRoutes()
+
+	// parts are the face moves and rotations of the moves, and owners the
+	// index of the move of each part.
+	var parts []Move
+	var owners []int
+	for i, m := range moves {
+		ms, err := expandMove(m)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range ms {
+			parts = append(parts, p)
+			owners = append(owners, i)
+		}
+	}
+
+	// step is the cheapest way to reach an orientation after a move: its
+	// time, number of primitives and previous orientation.
+	type step struct {
+		time time.Duration
+		n    int
+		prev orientation
+	}
+	better := func(s, t step) bool {
+		return s.time < t.time || s.time == t.time && s.n < t.n
+	}
+
+	steps := make([]map[orientation]step, len(parts)+1)
+	steps[0] = map[orientation]step{c.orientation(): {}}
+	for i, m := range parts {
+		steps[i+1] = map[orientation]step{}
+		for _, from := range orientations {
+			s, ok := steps[i][from]
+			if !ok {
+				continue
+			}
+			if isRotation(m) {
+				steps[i+1][from.rotate(m)] = step{s.time, s.n, from}
+				continue
+			}
+			d := dMoves[string(m[1:])]
+			for _, to := range orientations {
+				if to.position(m[0]) != Down {
+					continue
+				}
+				r := routes[from][to]
+				next := step{s.time + r.time + primitiveTimes[d], s.n + len(r.moves) + 1, from}
+				if old, ok := steps[i+1][to]; !ok || better(next, old) {
+					steps[i+1][to] = next
+				}
+			}
+		}
+	}
+
+	var end orientation
+	found := false
+	for _, o := range orientations {
+		s, ok := steps[len(parts)][o]
+		if ok && (!found || better(s, steps[len(parts)][end])) {
+			end, found = o, true
+		}
+	}
+	plan := make([][]string, len(moves))
+	for i := range plan {
+		plan[i] = []string{}
+	}
+	for i := len(parts); i > 0; i-- {
+		prev := steps[i][end].prev
+		if m := parts[i-1]; !isRotation(m) {
+			s := append(append([]string{}, routes[prev][end].moves...), dMoves[string(m[1:])])
+			plan[owners[i-1]] = append(s, plan[owners[i-1]]...)
+		}
+		end = prev
+	}
+	return plan, nil
+}
+
+func isFaceCode(b byte) bool {
+	for _, code := range faceCodes {
+		if code == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Do performs a primitive of the robot on the cube, e.g. to simulate the
+// robot.
+func (c *Cube) Do(p string) error {
+	switch p {
+	case MoveFlip:
+		c.flip()
+	case MoveTurn1, MoveTurn2, MoveRTurn:
+		c.turn(turnCount[p])
+	case MoveD:
+		c.D()
+	case MoveD2:
+		c.D2()
+	case Moved:
+		c.d()
+	default:
+		return fmt.Errorf("no such primitive: %s", p)
+	}
+	return nil
+}
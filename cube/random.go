@@ -0,0 +1,67 @@
+// Random cube states.
+//
+package cube
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// RandomCube returns a cube drawn uniformly from all the solvable states,
+// white up and green front. The pieces are permuted at random, with the parity
+// of the edges fixed up to match the corners, and twisted and flipped at
+// random except for the last corner and edge, which are fixed up so that the
+// twist and flip add up.
+func RandomCube(r *rand.Rand) *Cube {
+	cc := &cubies{}
+	copy(cc.cp[:], r.Perm(8))
+	copy(cc.ep[:], r.Perm(12))
+	if parity(cc.cp[:]) != parity(cc.ep[:]) {
+		cc.ep[0], cc.ep[1] = cc.ep[1], cc.ep[0]
+	}
+	twist, flip := 0, 0
+	for i := 0; i < 7; i++ {
+		cc.co[i] = r.Intn(3)
+		twist += cc.co[i]
+	}
+	cc.co[7] = (3 - twist%3) % 3
+	for i := 0; i < 11; i++ {
+		cc.eo[i] = r.Intn(2)
+		flip += cc.eo[i]
+	}
+	cc.eo[11] = flip % 2
+	return cc.cube()
+}
+
+// cube returns the cube of the pieces, the inverse of Cube.cubies.
+func (cc *cubies) cube() *Cube {
+	c := SolvedCube()
+	for i, facelets := range cornerFacelets {
+		name := cornerNames[cc.cp[i]]
+		for n, f := range facelets {
+			c.faces[f.code].Pieces[f.index] = faceColors[name[(n-cc.co[i]+3)%3]]
+		}
+	}
+	for i, facelets := range edgeFacelets {
+		name := edgeNames[cc.ep[i]]
+		for n, f := range facelets {
+			c.faces[f.code].Pieces[f.index] = faceColors[name[(n+cc.eo[i])%2]]
+		}
+	}
+	return c
+}
+
+// InvertMoves returns the moves which undo the moves.
+func InvertMoves(moves []string) []string {
+	inv := make([]string, len(moves))
+	for i, m := range moves {
+		switch {
+		case strings.HasSuffix(m, "'"):
+			m = strings.TrimSuffix(m, "'")
+		case !strings.HasSuffix(m, "2"):
+			m += "'"
+		}
+		inv[len(moves)-1-i] = m
+	}
+	return inv
+}
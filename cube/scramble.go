@@ -0,0 +1,95 @@
+// Scrambles: the cube after a move sequence in WCA notation, applied as a
+// person would and not through the robot, and renderings of the cube.
+//
+package cube
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// svgColors are the fill colors of the SVG rendering.
+var svgColors = map[Color]string{
+	White:   "#ffffff",
+	Red:     "#c41e3a",
+	Green:   "#009e60",
+	Blue:    "#0051ba",
+	Yellow:  "#ffd500",
+	Orange:  "#ff5800",
+	Unknown: "#808080",
+}
+
+// SolvedCube returns a solved cube in the color scheme of faceColors.
+func SolvedCube() *Cube {
+	c := NewCube()
+	for _, code := range faceCodes {
+		face := &Face{}
+		for i := range face.Pieces {
+			face.Pieces[i] = faceColors[code]
+		}
+		c.SetFace(code, face)
+	}
+	return c
+}
+
+// ParseMoves parses a move sequence in WCA notation, e.g. "R U R' U'" or
+// "M2 E2 S2". The moves are separated by whitespace or commas.
+func ParseMoves(s string) ([]Move, error) {
+	s = strings.NewReplacer("’", "'", "′", "'").Replace(s)
+	var moves []Move
+	for i, f := range splitFields(s) {
+		if _, err := expandMove(Move(f)); err != nil {
+			return nil, fmt.Errorf("move %d: %v", i+1, err)
+		}
+		moves = append(moves, Move(f))
+	}
+	return moves, nil
+}
+
+// Scramble parses the move sequence s and applies it to the cube as a person
+// would, not through the robot: the cube is turned back to its orientation
+// afterwards, so that each face is where the moves put it.
+func (c *Cube) Scramble(s string) ([]Move, error) {
+	moves, err := ParseMoves(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range moves {
+		if _, err := c.Rotate(m); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range allRoutes()[c.orientation()][NewCube().orientation()].moves {
+		c.Do(p)
+	}
+	return moves, nil
+}
+
+// SVG renders the cube as an SVG image, with the faces laid out as in Print.
+func (c *Cube) SVG() string {
+	const size, gap = 20, 2
+	const faceSize = 3*size + 2*gap
+
+	// origins are the columns and rows of the faces in the layout.
+	origins := map[byte][2]int{
+		Up:    {1, 0},
+		Left:  {0, 1},
+		Front: {1, 1},
+		Right: {2, 1},
+		Back:  {3, 1},
+		Down:  {1, 2},
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, 4*faceSize+3*gap, 3*faceSize+2*gap)
+	for _, code := range faceCodes {
+		o := origins[code]
+		x0, y0 := o[0]*(faceSize+gap), o[1]*(faceSize+gap)
+		for i, color := range c.faces[code].Pieces {
+			x, y := x0+i%3*(size+gap), y0+i/3*(size+gap)
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#000000"/>`, x, y, size, size, svgColors[color])
+		}
+	}
+	buf.WriteString("</svg>")
+	return buf.String()
+}
@@ -0,0 +1,294 @@
+// Piece-level validation of the cube state, so that an impossible cube is
+// rejected with a hint on how to fix it before it reaches the solver.
+//
+// The corners and edges are named by their positions as in Kociemba's solver,
+// e.g. the corner at URF touches the up, right and front faces.
+//
+package cube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validation error codes, one for each check.
+const (
+	ErrCodeColors  = "bad_color_count"
+	ErrCodeCenters = "bad_centers"
+	ErrCodeCorners = "bad_corners"
+	ErrCodeEdges   = "bad_edges"
+	ErrCodeTwist   = "twist_error"
+	ErrCodeFlip    = "flip_error"
+	ErrCodeParity  = "parity_error"
+)
+
+type facelet struct {
+	code  byte
+	index int
+}
+
+var (
+	cornerNames = []string{"URF", "UFL", "ULB", "UBR", "DFR", "DLF", "DBL", "DRB"}
+	edgeNames   = []string{"UR", "UF", "UL", "UB", "DR", "DF", "DL", "DB", "FR", "FL", "BL", "BR"}
+
+	// cornerFacelets lists the facelets of each corner position clockwise,
+	// starting with the up or down one.
+	cornerFacelets = [8][3]facelet{
+		{{Up, 8}, {Right, 0}, {Front, 2}},
+		{{Up, 6}, {Front, 0}, {Left, 2}},
+		{{Up, 0}, {Left, 0}, {Back, 2}},
+		{{Up, 2}, {Back, 0}, {Right, 2}},
+		{{Down, 2}, {Front, 8}, {Right, 6}},
+		{{Down, 0}, {Left, 8}, {Front, 6}},
+		{{Down, 6}, {Back, 8}, {Left, 6}},
+		{{Down, 8}, {Right, 8}, {Back, 6}},
+	}
+
+	// edgeFacelets lists the facelets of each edge position, starting with the
+	// one which defines the orientation.
+	edgeFacelets = [12][2]facelet{
+		{{Up, 5}, {Right, 1}},
+		{{Up, 7}, {Front, 1}},
+		{{Up, 3}, {Left, 1}},
+		{{Up, 1}, {Back, 1}},
+		{{Down, 5}, {Right, 7}},
+		{{Down, 1}, {Front, 7}},
+		{{Down, 3}, {Left, 7}},
+		{{Down, 7}, {Back, 7}},
+		{{Front, 5}, {Right, 3}},
+		{{Front, 3}, {Left, 5}},
+		{{Back, 5}, {Left, 3}},
+		{{Back, 3}, {Right, 5}},
+	}
+)
+
+// ValidationError tells which check of Validate failed and how to fix it.
+type ValidationError struct {
+	Code string
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+func invalid(code, format string, a ...interface{}) *ValidationError {
+	return &ValidationError{code, fmt.Sprintf(format, a...)}
+}
+
+// cubies is the cube on the piece level: the piece at each position and its
+// orientation.
+type cubies struct {
+	cp, co [8]int
+	ep, eo [12]int
+}
+
+// Validate checks that the cube is a solvable state of a real cube: 9
+// stickers of each color, distinct centers, every corner and edge exactly once,
+// and the corner twist, edge flip and permutation parity. The returned
+// *ValidationError says which piece is wrong.
+func (c *Cube) Validate() error {
+	if err := c.validateColors(); err != nil {
+		return err
+	}
+	codeOf := map[Color]byte{}
+	for _, code := range faceCodes {
+		color := c.faces[code].Pieces[4]
+		if other, ok := codeOf[color]; ok {
+			return invalid(ErrCodeCenters, "the centers of %c and %c are both %s", other, code, colorNames[color])
+		}
+		codeOf[color] = code
+	}
+	cc, err := c.cubies(codeOf)
+	if err != nil {
+		return err
+	}
+	return cc.verify()
+}
+
+func (c *Cube) validateColors() error {
+	count := map[Color]int{}
+	for _, code := range faceCodes {
+		for _, color := range c.faces[code].Pieces {
+			count[color]++
+		}
+	}
+	var wrong []string
+	for color := White; color < Unknown; color++ {
+		if count[color] != 9 {
+			wrong = append(wrong, fmt.Sprintf("%d %s", count[color], colorNames[color]))
+		}
+	}
+	if len(wrong) > 0 {
+		return invalid(ErrCodeColors, "there must be 9 stickers of each color, but found %s", strings.Join(wrong, ", "))
+	}
+	return nil
+}
+
+func (c *Cube) facelet(f facelet, codeOf map[Color]byte) byte {
+	return codeOf[c.faces[f.code].Pieces[f.index]]
+}
+
+// cubies finds the piece at each position. codeOf maps the center colors to
+// their faces.
+func (c *Cube) cubies(codeOf map[Color]byte) (*cubies, error) {
+	cc := &cubies{}
+
+	seen := map[int][]string{}
+	for i, facelets := range cornerFacelets {
+		var codes [3]byte
+		for n, f := range facelets {
+			codes[n] = c.facelet(f, codeOf)
+		}
+		ori := 0
+		for ori < 3 && codes[ori] != Up && codes[ori] != Down {
+			ori++
+		}
+		j := -1
+		if ori < 3 {
+			name := string([]byte{codes[ori], codes[(ori+1)%3], codes[(ori+2)%3]})
+			j = indexOf(cornerNames, name)
+		}
+		if j < 0 {
+			return nil, invalid(ErrCodeCorners, "the corner at %s has colors %s which no corner has", cornerNames[i], c.colorsOf(facelets[:]))
+		}
+		cc.cp[i], cc.co[i] = j, ori
+		seen[j] = append(seen[j], cornerNames[i])
+	}
+	if err := checkOnce(seen, cornerNames, "corner"); err != nil {
+		return nil, invalid(ErrCodeCorners, "%v", err)
+	}
+
+	seen = map[int][]string{}
+	for i, facelets := range edgeFacelets {
+		a, b := c.facelet(facelets[0], codeOf), c.facelet(facelets[1], codeOf)
+		j, ori := indexOf(edgeNames, string([]byte{a, b})), 0
+		if j < 0 {
+			j, ori = indexOf(edgeNames, string([]byte{b, a})), 1
+		}
+		if j < 0 {
+			return nil, invalid(ErrCodeEdges, "the edge at %s has colors %s which no edge has", edgeNames[i], c.colorsOf(facelets[:]))
+		}
+		cc.ep[i], cc.eo[i] = j, ori
+		seen[j] = append(seen[j], edgeNames[i])
+	}
+	if err := checkOnce(seen, edgeNames, "edge"); err != nil {
+		return nil, invalid(ErrCodeEdges, "%v", err)
+	}
+	return cc, nil
+}
+
+func (c *Cube) colorsOf(facelets []facelet) string {
+	names := make([]string, len(facelets))
+	for i, f := range facelets {
+		names[i] = colorNames[c.faces[f.code].Pieces[f.index]]
+	}
+	return strings.Join(names, "/")
+}
+
+func indexOf(names []string, name string) int {
+	for i := range names {
+		if names[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkOnce checks that each piece was seen at exactly one position.
+func checkOnce(seen map[int][]string, names []string, kind string) error {
+	var twice []int
+	for j, at := range seen {
+		if len(at) > 1 {
+			twice = append(twice, j)
+		}
+	}
+	if len(twice) == 0 {
+		return nil
+	}
+	sort.Ints(twice)
+	j := twice[0]
+	var missing []string
+	for i := range names {
+		if len(seen[i]) == 0 {
+			missing = append(missing, names[i])
+		}
+	}
+	return fmt.Errorf("the %s %s is at both %s, but %s %s is missing",
+		kind, names[j], strings.Join(seen[j], " and "), kind, strings.Join(missing, " and "))
+}
+
+// verify checks the twist, flip and parity of a cube whose pieces all exist
+// exactly once.
+func (cc *cubies) verify() error {
+	twist := 0
+	for _, co := range cc.co {
+		twist += co
+	}
+	if twist%3 != 0 {
+		// Twisting back a corner which is twisted by exactly the excess
+		// probably fixes a mistyped corner.
+		i := 0
+		for i < 8 && cc.co[i] != twist%3 {
+			i++
+		}
+		if i == 8 {
+			i = 0
+		}
+		dir := "counterclockwise"
+		if twist%3 == 2 {
+			dir = "clockwise"
+		}
+		return invalid(ErrCodeTwist, "the corner at %s must be twisted %s", cornerNames[i], dir)
+	}
+
+	flip := 0
+	for _, eo := range cc.eo {
+		flip += eo
+	}
+	if flip%2 != 0 {
+		i := 0
+		for i < 12 && cc.eo[i] == 0 {
+			i++
+		}
+		return invalid(ErrCodeFlip, "the edge at %s must be flipped", edgeNames[i])
+	}
+
+	if parity(cc.cp[:]) != parity(cc.ep[:]) {
+		if i, k := misplaced(cc.ep[:]); i >= 0 {
+			return invalid(ErrCodeParity, "edges %s and %s must be swapped", edgeNames[i], edgeNames[k])
+		}
+		i, k := misplaced(cc.cp[:])
+		return invalid(ErrCodeParity, "corners %s and %s must be swapped", cornerNames[i], cornerNames[k])
+	}
+	return nil
+}
+
+func parity(perm []int) int {
+	s := 0
+	for i := range perm {
+		for j := 0; j < i; j++ {
+			if perm[j] > perm[i] {
+				s++
+			}
+		}
+	}
+	return s % 2
+}
+
+// misplaced returns the first position i whose piece isn't at home, and the
+// position k of the piece which belongs to i. It returns -1, -1 if all pieces
+// are at home.
+func misplaced(perm []int) (int, int) {
+	for i := range perm {
+		if perm[i] != i {
+			for k := range perm {
+				if perm[k] == i {
+					return i, k
+				}
+			}
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,114 @@
+// WCA move notation: slice moves, wide moves and whole-cube rotations.
+//
+// The robot only turns outer faces, so the other moves are expanded into face
+// moves and rotations:
+//
+//   M = R L' x'    E = U D' y'    S = F' B z
+//   Rw = L x       Uw = D y       Fw = B z
+//   Lw = R x'      Dw = U y'      Bw = F z'
+//
+// Wide moves can also be written in lowercase, e.g. r for Rw. A rotation
+// doesn't move the cube in the robot, it only changes which virtual face is
+// where, so it updates calibs and costs no physical move.
+//
+package cube
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var (
+	// rotations map the face each rotation moves to the face it moves it
+	// to, e.g. x moves the front face to the top.
+	rotations = map[byte]map[byte]byte{
+		'x': {Front: Up, Up: Back, Back: Down, Down: Front},
+		'y': {Front: Left, Left: Back, Back: Right, Right: Front},
+		'z': {Up: Right, Right: Down, Down: Left, Left: Up},
+	}
+
+	// wideMoves and sliceMoves are the face moves and rotations of each
+	// move, clockwise.
+	wideMoves = map[byte][]Move{
+		Right: {"L", "x"},
+		Left:  {"R", "x'"},
+		Up:    {"D", "y"},
+		Down:  {"U", "y'"},
+		Front: {"B", "z"},
+		Back:  {"F", "z'"},
+	}
+	sliceMoves = map[byte][]Move{
+		'M': {"R", "L'", "x'"},
+		'E': {"U", "D'", "y'"},
+		'S': {"F'", "B", "z"},
+	}
+)
+
+// suffixTurns maps the suffix of a move to its number of clockwise quarter
+// turns, and quarterSuffixes back.
+var (
+	suffixTurns = map[string]int{
+		"":  1,
+		"2": 2,
+		"'": 3,
+	}
+	quarterSuffixes = [4]string{"", "", "2", "'"}
+)
+
+// expandMove expands a move into face moves and rotations, which are returned
+// as they are. The parts of a slice or wide move turn around the same axis, so
+// they commute and are each turned as many times as the move.
+func expandMove(m Move) ([]Move, error) {
+	s := string(m)
+	var parts []Move
+	switch {
+	case s == "":
+	case len(s) > 1 && s[1] == 'w' && isFaceCode(s[0]):
+		parts, s = wideMoves[s[0]], s[2:]
+	case strings.IndexByte("rludfb", s[0]) >= 0:
+		parts, s = wideMoves[s[0]-'a'+'A'], s[1:]
+	case sliceMoves[s[0]] != nil:
+		parts, s = sliceMoves[s[0]], s[1:]
+	case isFaceCode(s[0]) || isRotation(m):
+		if _, ok := suffixTurns[s[1:]]; ok {
+			return []Move{m}, nil
+		}
+	}
+	n, ok := suffixTurns[s]
+	if parts == nil || !ok {
+		return nil, fmt.Errorf("no such move: %s", m)
+	}
+	moves := make([]Move, len(parts))
+	for i, p := range parts {
+		moves[i] = p[:1] + Move(quarterSuffixes[suffixTurns[string(p[1:])]*n%4])
+	}
+	return moves, nil
+}
+
+// isRotation reports whether m is a whole-cube rotation.
+func isRotation(m Move) bool {
+	return len(m) > 0 && rotations[m[0]] != nil
+}
+
+// rotate rotates the virtual cube with the rotation m, e.g. x', by moving the
+// calibs of the virtual faces.
+func (c *Cube) rotate(m Move) {
+	o := c.orientation().rotate(m)
+	for i, code := range faceCodes {
+		c.calibs[code] = o[i]
+	}
+}
+
+// rotate returns the orientation after the rotation m. The physical faces
+// don't move, the virtual faces take the positions of the ones they replace.
+func (o orientation) rotate(m Move) orientation {
+	for n := suffixTurns[string(m[1:])]; n > 0; n-- {
+		next := o
+		for from, to := range rotations[m[0]] {
+			next[bytes.IndexByte(faceCodes, to)] = o.position(from)
+		}
+		o = next
+	}
+	return o
+}
@@ -0,0 +1,3 @@
+module github.com/ross-wu/cube
+
+go 1.21
@@ -51,6 +51,11 @@ char* solutionToString(search_t* search, int length, int depthPhase1);
  */
 char* solution(char* facelets, int maxDepth, long timeOut, int useSeparator, const char* cache_dir);
 
+// Same as solution, but the search is also aborted and returns "Error 8" as
+// soon as *cancel is set to a nonzero value by another thread. cancel may be
+// NULL.
+char* solutionWithCancel(char* facelets, int maxDepth, long timeOut, int useSeparator, const char* cache_dir, volatile int* cancel);
+
 // Apply phase2 of algorithm and return the combined phase1 and phase2 depth. In phase2, only the moves
 // U,D,R2,F2,L2 and B2 are allowed.
 int totalDepth(search_t* search, int depthPhase1, int maxDepth);
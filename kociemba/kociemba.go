@@ -0,0 +1,91 @@
+// Package kociemba is a cgo binding of the C solver in this directory, so that
+// it can run in process and load its tables only once.
+package kociemba
+
+/*
+#cgo CFLAGS: -std=c99 -O3 -D_POSIX_C_SOURCE=200809L -I${SRCDIR}/include
+#include <stdlib.h>
+#include "coordcube.h"
+#include "search.h"
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Error is an error code returned by solution(), e.g. 3 for "Error 3", see
+// include/search.h.
+type Error int
+
+func (e Error) Error() string {
+	return fmt.Sprintf("Error %d", int(e))
+}
+
+var initOnce sync.Once
+
+// Init loads the move and pruning tables from cacheDir, or computes them and
+// saves them to cacheDir. Tables are loaded once per process.
+func Init(cacheDir string) {
+	initOnce.Do(func() {
+		dir := C.CString(cacheDir)
+		defer C.free(unsafe.Pointer(dir))
+		C.initPruning(dir)
+	})
+}
+
+// Solution calls solution() of search.c, see include/search.h, and returns
+// its error codes as Error. It's safe to call concurrently, since the tables
+// are read only once loaded by Init.
+func Solution(facelets string, maxDepth int, timeout time.Duration, useSeparator bool, cacheDir string) (string, error) {
+	return SolutionContext(context.Background(), facelets, maxDepth, timeout, useSeparator, cacheDir)
+}
+
+// SolutionContext is Solution with the search aborted when ctx is done, it
+// returns ctx.Err() then. The timeout of solution() is rounded up to seconds,
+// set a deadline on ctx for a finer timeout.
+func SolutionContext(ctx context.Context, facelets string, maxDepth int, timeout time.Duration, useSeparator bool, cacheDir string) (string, error) {
+	Init(cacheDir)
+	// solution() reads 54 chars without checking the length.
+	if len(facelets) != 54 {
+		return "", Error(1)
+	}
+
+	f := C.CString(facelets)
+	defer C.free(unsafe.Pointer(f))
+	dir := C.CString(cacheDir)
+	defer C.free(unsafe.Pointer(dir))
+	sep := C.int(0)
+	if useSeparator {
+		sep = 1
+	}
+
+	// The search polls cancel, which is set from another goroutine when ctx
+	// is done. It's Go memory, so it's fine if it's set after solution()
+	// returned.
+	var cancel int32
+	stop := context.AfterFunc(ctx, func() {
+		atomic.StoreInt32(&cancel, 1)
+	})
+	defer stop()
+
+	secs := C.long((timeout + time.Second - 1) / time.Second)
+	s := C.solutionWithCancel(f, C.int(maxDepth), secs, sep, dir, (*C.int)(unsafe.Pointer(&cancel)))
+	defer C.free(unsafe.Pointer(s))
+	sol := C.GoString(s)
+	if strings.HasPrefix(sol, "Error ") {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		var code int
+		fmt.Sscanf(sol, "Error %d", &code)
+		return "", Error(code)
+	}
+	return sol, nil
+}
@@ -9,6 +9,14 @@
 #define MIN(a, b) (((a)<(b))?(a):(b))
 #define MAX(a, b) (((a)>(b))?(a):(b))
 
+// errorString returns "Error code" in a new string, the error result of solution.
+static char* errorString(int code)
+{
+    char* s = (char*) malloc(16);
+    sprintf(s, "Error %d", code);
+    return s;
+}
+
 char* solutionToString(search_t* search, int length, int depthPhase1)
 {
     char* s = (char*) calloc(length * 3 + 5, 1);
@@ -57,6 +65,11 @@ char* solutionToString(search_t* search, int length, int depthPhase1)
 
 
 char* solution(char* facelets, int maxDepth, long timeOut, int useSeparator, const char* cache_dir)
+{
+    return solutionWithCancel(facelets, maxDepth, timeOut, useSeparator, cache_dir, NULL);
+}
+
+char* solutionWithCancel(char* facelets, int maxDepth, long timeOut, int useSeparator, const char* cache_dir, volatile int* cancel)
 {
     search_t* search = (search_t*) calloc(1, sizeof(search_t));
     facecube_t* fc;
@@ -100,14 +113,16 @@ char* solution(char* facelets, int maxDepth, long timeOut, int useSeparator, con
     for (i = 0; i < 6; i++)
         if (count[i] != 9) {
             free(search);
-            return NULL;
+            return errorString(1);
         }
 
     fc = get_facecube_fromstring(facelets);
     cc = toCubieCube(fc);
     if ((s = verify(cc)) != 0) {
+        free((void*) fc);
+        free((void*) cc);
         free(search);
-        return NULL;
+        return errorString(-s);
     }
 
     // +++++++++++++++++++++++ initialization +++++++++++++++++++++++++++++++++
@@ -146,13 +161,22 @@ char* solution(char* facelets, int maxDepth, long timeOut, int useSeparator, con
                 do {// increment axis
                     if (++search->ax[n] > 5) {
 
-                        if (time(NULL) - tStart > timeOut)
-                            return NULL;
+                        if (time(NULL) - tStart > timeOut || (cancel != NULL && *cancel)) {
+                            free((void*) fc);
+                            free((void*) cc);
+                            free((void*) c);
+                            free((void*) search);
+                            return errorString(8);
+                        }
 
                         if (n == 0) {
-                            if (depthPhase1 >= maxDepth)
-                                return NULL;
-                            else {
+                            if (depthPhase1 >= maxDepth) {
+                                free((void*) fc);
+                                free((void*) cc);
+                                free((void*) c);
+                                free((void*) search);
+                                return errorString(7);
+                            } else {
                                 depthPhase1++;
                                 search->ax[n] = 0;
                                 search->po[n] = 1;
@@ -1,30 +1,57 @@
+//go:build ignore
+// +build ignore
+
+// The command line solver built by the Makefile, excluded from the Go package.
+
 #include <stdio.h>
 #include <stdlib.h>
+#include <string.h>
 #include "search.h"
 
+// Usage: kociemba [-d maxDepth] [-t timeout] facelets [pattern]
+//
+// The default maxDepth is 24 and the default timeout is 1000 seconds.
 int main(int argc, char **argv)
 {
-    if (argc > 1) {
+    int maxDepth = 24;
+    long timeOut = 1000;
+    int i = 1;
+
+    for (; i + 1 < argc && argv[i][0] == '-'; i += 2) {
+        if (strcmp(argv[i], "-d") == 0) {
+            maxDepth = atoi(argv[i + 1]);
+        } else if (strcmp(argv[i], "-t") == 0) {
+            timeOut = atol(argv[i + 1]);
+        } else {
+            fprintf(stderr, "unknown option %s\n", argv[i]);
+            return 1;
+        }
+    }
+    if (maxDepth < 1 || maxDepth > 30) {
+        fprintf(stderr, "maxDepth must be between 1 and 30\n");
+        return 1;
+    }
+
+    if (i < argc) {
         char patternized[64];
-        char* facelets = argv[1];
-        if (argc > 2) {
-            patternize(facelets, argv[2], patternized);
+        char* facelets = argv[i];
+        if (i + 1 < argc) {
+            patternize(facelets, argv[i + 1], patternized);
             facelets = patternized;
         }
         char *sol = solution(
             facelets,
-            24,
-            1000,
+            maxDepth,
+            timeOut,
             0,
             "cache"
         );
-        if (sol == NULL) {
-            puts("Unsolvable cube!");
-            return 2;
-        }
+        // The error codes of solution() are printed like the solutions,
+        // e.g. "Error 3", with the exit status 2.
         puts(sol);
+        int status = strncmp(sol, "Error", 5) == 0 ? 2 : 0;
         free(sol);
-        return 0;
+        return status;
     } else {
         return 1;
     }
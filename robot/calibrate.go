@@ -0,0 +1,235 @@
+// Calibration of the geometry of a new build.
+//
+// Calibrate jogs each motor from lines read from the keyboard, and records
+// its positions one by one:
+//
+//   +, -      jog the motor by 10 degrees
+//   +N, -N    jog the motor by N degrees
+//   (empty)   keep the position
+//   q         quit the calibration
+//
+// Each position is then tested with a repeated motion, and jogged again until
+// the motion looks right.
+//
+package robot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// jogStep is the default jog of a motor, in degrees.
+	jogStep = 10
+	// testRuns is the number of times a calibrated position is tested.
+	testRuns = 3
+)
+
+// ErrCanceled is the error of a calibration which was quit.
+var ErrCanceled = errors.New("calibration canceled")
+
+// Calibrate jogs the motors from the commands read from in, and returns the
+// geometry of the robot with the positions recorded. The robot moves with the
+// new geometry afterwards. The cube should be on the turntable, square to the
+// flip arm.
+func (r *Robot) Calibrate(in io.Reader) (Geometry, error) {
+	c := &calibrator{r: r, in: bufio.NewReader(in), geo: r.geo}
+	if err := c.calibrate(); err != nil {
+		return Geometry{}, err
+	}
+	if err := c.geo.Validate(); err != nil {
+		return Geometry{}, fmt.Errorf("the calibrated geometry is invalid: %v", err)
+	}
+	r.geo = c.geo
+	return c.geo, nil
+}
+
+type calibrator struct {
+	r   *Robot
+	in  *bufio.Reader
+	geo Geometry
+}
+
+func (c *calibrator) calibrate() error {
+	r, f, e := c.r, &c.geo.Flip, &c.geo.Eye
+	var err error
+	fmt.Printf("Flip arm.\n")
+	if f.Release, err = c.position("flip", r.flipMotor, f.Hold, f.Release, "Jog the flip arm just clear of the cube"); err != nil {
+		return err
+	}
+	if f.Hold, err = c.position("flip", r.flipMotor, f.Release, f.Hold, "Jog the flip arm to hold the upper layers of the cube"); err != nil {
+		return err
+	}
+	if f.Flip, err = c.position("flip", r.flipMotor, f.Release, f.Flip, "Jog the flip arm to tip the cube over"); err != nil {
+		return err
+	}
+	if err := r.runTo("flip", r.flipMotor, f.Release); err != nil {
+		return err
+	}
+
+	fmt.Printf("Turntable.\n")
+	if err := c.quarter(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Eye.\n")
+	if e.Center, err = c.position("eye", r.eyeMotor, 0, e.Center, "Jog the color sensor over the center of the top face"); err != nil {
+		return err
+	}
+	if e.Edge, err = c.position("eye", r.eyeMotor, 0, e.Edge, "Jog the color sensor over an edge of the top face"); err != nil {
+		return err
+	}
+	// The corners are under the eye with the turntable an eighth off square.
+	eighth := c.geo.Turn.Quarter / 2
+	if err := r.runTo("turn", r.turnMotor, eighth); err != nil {
+		return err
+	}
+	if e.Corner, err = c.position("eye", r.eyeMotor, 0, e.Corner, "Jog the color sensor over a corner of the top face"); err != nil {
+		return err
+	}
+	if err := r.runTo("eye", r.eyeMotor, 0); err != nil {
+		return err
+	}
+	return r.runTo("turn", r.turnMotor, 0)
+}
+
+// quarter finds the rotation of the turn motor for a quarter of the
+// turntable, from a full turn of it, and zeroes the turn motor square.
+func (c *calibrator) quarter() error {
+	r, t := c.r, &c.geo.Turn
+	start := r.turnPos
+	for {
+		pos, err := c.jog("turn", r.turnMotor, start+4*t.Quarter, "Jog the turntable a full turn, until it's square again")
+		if err != nil {
+			return err
+		}
+		quarter := roundDiv(pos-start, 4)
+		if quarter <= 0 {
+			fmt.Printf("The turntable turned %d degrees, jog it forward.\n", pos-start)
+			continue
+		}
+		if err := r.runTo("turn", r.turnMotor, start); err != nil {
+			return err
+		}
+		fmt.Printf("Testing %d degrees a quarter.\n", quarter)
+		if ok, err := c.test("turn", r.turnMotor, start, start+quarter); err != nil {
+			return err
+		} else if ok {
+			t.Quarter = quarter
+			break
+		}
+	}
+	if err := r.turnMotor.Command("reset"); err != nil {
+		return fmt.Errorf("turn motor: can't reset: %v", err)
+	}
+	if err := r.hold("turn", r.turnMotor, r.opts.Profile.Turn); err != nil {
+		return fmt.Errorf("can't set up the turn motor: %v", err)
+	}
+	r.quarters, r.turnPos, r.lag = 0, 0, 0
+	return nil
+}
+
+// position jogs the motor to a position from pos, and tests it with motions
+// from rest, until the motion looks right.
+func (c *calibrator) position(name string, motor Motor, rest, pos int, prompt string) (int, error) {
+	for {
+		var err error
+		if pos, err = c.jog(name, motor, pos, prompt); err != nil {
+			return 0, err
+		}
+		fmt.Printf("Testing %d.\n", pos)
+		if ok, err := c.test(name, motor, rest, pos); err != nil {
+			return 0, err
+		} else if ok {
+			return pos, nil
+		}
+	}
+}
+
+// test runs the motor from rest to pos and back testRuns times, and asks
+// whether the motion looks right.
+func (c *calibrator) test(name string, motor Motor, rest, pos int) (bool, error) {
+	for i := 0; i < testRuns; i++ {
+		for _, p := range []int{pos, rest} {
+			if err := c.r.runTo(name, motor, p); err != nil {
+				fmt.Printf("The test failed: %v\n", err)
+				return false, nil
+			}
+		}
+	}
+	for {
+		line, err := c.read("Does it move right? [y/n]")
+		if err != nil {
+			return false, err
+		}
+		switch line {
+		case "y", "":
+			return true, nil
+		case "n":
+			return false, nil
+		}
+	}
+}
+
+// jog runs the motor to pos, and jogs it until the position is kept.
+func (c *calibrator) jog(name string, motor Motor, pos int, prompt string) (int, error) {
+	if err := c.r.runTo(name, motor, pos); err != nil {
+		fmt.Printf("Can't run to %d: %v\n", pos, err)
+		if pos, err = motor.Position(); err != nil {
+			return 0, fmt.Errorf("%s motor: can't get position: %v", name, err)
+		}
+	}
+	for {
+		line, err := c.read(fmt.Sprintf("%s, at %d", prompt, pos))
+		if err != nil {
+			return 0, err
+		}
+		if line == "" {
+			return pos, nil
+		}
+		step := jogStep
+		if len(line) > 1 {
+			if step, err = strconv.Atoi(line[1:]); err != nil || step <= 0 {
+				fmt.Printf("Invalid jog %q.\n", line)
+				continue
+			}
+		}
+		switch line[0] {
+		case '+':
+		case '-':
+			step = -step
+		default:
+			fmt.Printf("Unknown command %q: +, -, +N, -N, enter or q.\n", line)
+			continue
+		}
+		if err := c.r.runTo(name, motor, pos+step); err != nil {
+			fmt.Printf("Can't jog: %v\n", err)
+			if pos, err = motor.Position(); err != nil {
+				return 0, fmt.Errorf("%s motor: can't get position: %v", name, err)
+			}
+			continue
+		}
+		pos += step
+	}
+}
+
+// read prompts for a line, and returns ErrCanceled if it's q.
+func (c *calibrator) read(prompt string) (string, error) {
+	fmt.Printf("%s: ", prompt)
+	line, err := c.in.ReadString('\n')
+	if err != nil && line == "" {
+		if err == io.EOF {
+			return "", ErrCanceled
+		}
+		return "", fmt.Errorf("can't read the input: %v", err)
+	}
+	line = strings.TrimSpace(line)
+	if line == "q" {
+		return "", ErrCanceled
+	}
+	return line, nil
+}
@@ -0,0 +1,57 @@
+// Client of the solver server.
+//
+package robot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type solveRequest struct {
+	Faces map[string]string `json:"faces"`
+}
+
+type solveResponse struct {
+	Moves []string `json:"moves"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// RequestMoves asks the solver server at addr, "host:port", for the physical
+// moves which solve the cube of the faces, keyed by the face codes U, L, F, R,
+// B and D.
+func RequestMoves(addr string, faces map[string]string) ([]string, error) {
+	body, err := json.Marshal(&solveRequest{Faces: faces})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("http://%s/api/v1/solve", addr)
+	log.Printf("POST %s %s", url, body)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("ERROR: POST error: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("Response:\nStatus: %d: %s\n\n", resp.StatusCode, resp.Status)
+	var sresp solveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sresp); err != nil {
+		log.Printf("ERROR: read body error: %v", err)
+		return nil, err
+	}
+	if sresp.Error != nil {
+		log.Printf("ERROR: Can't get solution from server: %s: %s\n", sresp.Error.Code, sresp.Error.Message)
+		return nil, fmt.Errorf("%s: %s", sresp.Error.Code, sresp.Error.Message)
+	}
+
+	steps := sresp.Moves
+	log.Printf("steps: %+v\n", steps)
+
+	return steps, nil
+}
@@ -0,0 +1,165 @@
+// Package ev3 connects the robot to the motors and the color sensor of a LEGO
+// EV3 running ev3dev, through the sysfs attributes of its drivers, see
+// https://docs.ev3dev.org/projects/lego-linux-drivers/en/ev3dev-stretch/:
+//
+//   flip, err := ev3.TachoMotor("A", ev3.LargeMotor)
+//   sensor, err := ev3.ColorSensor("4")
+//
+package ev3
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ross-wu/cube/robot"
+)
+
+// MotorDriver is the ev3dev driver of a motor.
+type MotorDriver string
+
+const (
+	LargeMotor  MotorDriver = "lego-ev3-l-motor"
+	MediumMotor MotorDriver = "lego-ev3-m-motor"
+
+	colorSensorDriver = "lego-ev3-color"
+)
+
+// sysfs is the directory of the device classes, a fake one in the tests.
+var sysfs = "/sys/class"
+
+// device is the sysfs directory of a motor or a sensor.
+type device string
+
+// find returns the device of the class, e.g. tacho-motor, at the port with
+// the driver.
+func find(class, port, driver string) (device, error) {
+	dirs, err := filepath.Glob(filepath.Join(sysfs, class, "*"))
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range dirs {
+		d := device(dir)
+		if address, err := d.read("address"); err != nil || address != port {
+			continue
+		}
+		if name, err := d.read("driver_name"); err == nil && name == driver {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("no %s %s at %s", class, driver, port)
+}
+
+func (d device) read(attr string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(string(d), attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func (d device) readInt(attr string) (int, error) {
+	s, err := d.read(attr)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q of %s: %v", attr, s, d, err)
+	}
+	return n, nil
+}
+
+// write writes the attribute like "echo value > attr", except that the
+// attribute isn't created: only the driver creates them.
+func (d device) write(attr, value string) error {
+	f, err := os.OpenFile(filepath.Join(string(d), attr), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+		return fmt.Errorf("can't write %q to %s: %v", value, f.Name(), err)
+	}
+	return f.Close()
+}
+
+// motor is a robot.Motor on a tacho motor.
+type motor struct {
+	d device
+}
+
+func (m motor) Command(cmd string) error          { return m.d.write("command", cmd) }
+func (m motor) SetPositionSetpoint(pos int) error { return m.d.write("position_sp", strconv.Itoa(pos)) }
+func (m motor) SetSpeedSetpoint(speed int) error  { return m.d.write("speed_sp", strconv.Itoa(speed)) }
+func (m motor) SetStopAction(action string) error { return m.d.write("stop_action", action) }
+func (m motor) Position() (int, error)            { return m.d.readInt("position") }
+
+func (m motor) SetRampUpSetpoint(d time.Duration) error {
+	return m.d.write("ramp_up_sp", strconv.Itoa(int(d/time.Millisecond)))
+}
+
+func (m motor) SetRampDownSetpoint(d time.Duration) error {
+	return m.d.write("ramp_down_sp", strconv.Itoa(int(d/time.Millisecond)))
+}
+
+// motorStates map the flags of the state attribute to the ones of the robot.
+var motorStates = map[string]robot.MotorState{
+	"running":    robot.Running,
+	"ramping":    robot.Ramping,
+	"holding":    robot.Holding,
+	"overloaded": robot.Overloaded,
+	"stalled":    robot.Stalled,
+}
+
+func (m motor) State() (robot.MotorState, error) {
+	s, err := m.d.read("state")
+	if err != nil {
+		return 0, err
+	}
+	var state robot.MotorState
+	for _, flag := range strings.Fields(s) {
+		state |= motorStates[flag]
+	}
+	return state, nil
+}
+
+// TachoMotor returns the motor at the output port, A to D.
+func TachoMotor(port string, driver MotorDriver) (robot.Motor, error) {
+	d, err := find("tacho-motor", "ev3-ports:out"+port, string(driver))
+	if err != nil {
+		return nil, err
+	}
+	return motor{d}, nil
+}
+
+// colorSensor is a robot.ColorSensor on a color sensor in RGB-RAW mode.
+type colorSensor struct {
+	d device
+}
+
+func (s colorSensor) RGB() (r, g, b int, err error) {
+	var rgb [3]int
+	for i := range rgb {
+		if rgb[i], err = s.d.readInt(fmt.Sprintf("value%d", i)); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return rgb[0], rgb[1], rgb[2], nil
+}
+
+// ColorSensor returns the color sensor at the input port, 1 to 4.
+func ColorSensor(port string) (robot.ColorSensor, error) {
+	ev3port := "ev3-ports:in" + port
+	d, err := find("lego-sensor", ev3port, colorSensorDriver)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.write("mode", "RGB-RAW"); err != nil {
+		return nil, fmt.Errorf("can't set the color sensor %q to RGB-RAW: %v", ev3port, err)
+	}
+	return colorSensor{d}, nil
+}
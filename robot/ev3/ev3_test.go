@@ -0,0 +1,112 @@
+package ev3
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ross-wu/cube/robot"
+)
+
+// fakeDevice creates a device of the class in a fake sysfs, with the
+// attributes.
+func fakeDevice(t *testing.T, class, name string, attrs map[string]string) device {
+	t.Helper()
+	dir := filepath.Join(sysfs, class, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for attr, value := range attrs {
+		if err := os.WriteFile(filepath.Join(dir, attr), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return device(dir)
+}
+
+func useFakeSysfs(t *testing.T) {
+	t.Helper()
+	saved := sysfs
+	sysfs = t.TempDir()
+	t.Cleanup(func() { sysfs = saved })
+}
+
+func TestTachoMotor(t *testing.T) {
+	useFakeSysfs(t)
+	attrs := map[string]string{"command": "", "position_sp": "", "speed_sp": "", "stop_action": "", "ramp_up_sp": "", "ramp_down_sp": ""}
+	// A medium motor at the same port, and the motor at another port.
+	fakeDevice(t, "tacho-motor", "motor0", map[string]string{"address": "ev3-ports:outA", "driver_name": string(MediumMotor)})
+	fakeDevice(t, "tacho-motor", "motor1", map[string]string{"address": "ev3-ports:outB", "driver_name": string(LargeMotor)})
+	attrs["address"], attrs["driver_name"] = "ev3-ports:outA", string(LargeMotor)
+	attrs["position"], attrs["state"] = "-42", "running ramping stalled"
+	d := fakeDevice(t, "tacho-motor", "motor2", attrs)
+
+	m, err := TachoMotor("A", LargeMotor)
+	if err != nil {
+		t.Fatalf("TachoMotor(A) error: %v", err)
+	}
+	for _, err := range []error{
+		m.SetPositionSetpoint(-135),
+		m.SetSpeedSetpoint(400),
+		m.SetStopAction("hold"),
+		m.SetRampUpSetpoint(300 * time.Millisecond),
+		m.SetRampDownSetpoint(time.Second),
+		m.Command("run-to-abs-pos"),
+	} {
+		if err != nil {
+			t.Fatalf("motor error: %v", err)
+		}
+	}
+	for attr, want := range map[string]string{
+		"position_sp":  "-135",
+		"speed_sp":     "400",
+		"stop_action":  "hold",
+		"ramp_up_sp":   "300",
+		"ramp_down_sp": "1000",
+		"command":      "run-to-abs-pos",
+	} {
+		if got, err := d.read(attr); got != want || err != nil {
+			t.Errorf("%s = %q, %v, want %q", attr, got, err, want)
+		}
+	}
+	if pos, err := m.Position(); pos != -42 || err != nil {
+		t.Errorf("Position() = %d, %v, want -42", pos, err)
+	}
+	if state, err := m.State(); state != robot.Running|robot.Ramping|robot.Stalled || err != nil {
+		t.Errorf("State() = %b, %v, want running, ramping and stalled", state, err)
+	}
+
+	if _, err := TachoMotor("C", LargeMotor); err == nil {
+		t.Errorf("TachoMotor(C) without a motor succeeded")
+	}
+	// Attributes can't be created.
+	if err := d.write("no_such_attr", "1"); err == nil {
+		t.Errorf("writing a missing attribute succeeded")
+	}
+}
+
+func TestColorSensor(t *testing.T) {
+	useFakeSysfs(t)
+	d := fakeDevice(t, "lego-sensor", "sensor0", map[string]string{
+		"address":     "ev3-ports:in4",
+		"driver_name": colorSensorDriver,
+		"mode":        "COL-REFLECT",
+		"value0":      "195",
+		"value1":      "236",
+		"value2":      "237",
+	})
+	s, err := ColorSensor("4")
+	if err != nil {
+		t.Fatalf("ColorSensor(4) error: %v", err)
+	}
+	if mode, _ := d.read("mode"); mode != "RGB-RAW" {
+		t.Errorf("mode %q, want RGB-RAW", mode)
+	}
+	if r, g, b, err := s.RGB(); r != 195 || g != 236 || b != 237 || err != nil {
+		t.Errorf("RGB() = %d/%d/%d, %v, want 195/236/237", r, g, b, err)
+	}
+	if _, err := ColorSensor("1"); err == nil {
+		t.Errorf("ColorSensor(1) without a sensor succeeded")
+	}
+}
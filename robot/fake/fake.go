@@ -0,0 +1,349 @@
+// Package fake is an in-memory backend of the robot, to test the robot without
+// an EV3.
+//
+// Motor and ColorSensor keep what the robot sets and commands, and Robot
+// simulates the whole robot over a cube: its motors flip and turn the cube
+// like the flip arm and the turntable of the real robot. Its motors run at
+// once, or take the time of the real ones after Timed.
+//
+//   sim := fake.NewRobot(cube.SolvedCube())
+//   r := robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep})
+//   r.Solve(moves)
+//   solved := sim.Cube.IsSolved()
+//
+package fake
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/robot"
+)
+
+// Motor is a motor which runs to its setpoint at once, or at its speed in the
+// time of its Clock if set, or gets stuck halfway.
+type Motor struct {
+	// Pos is the position of the motor, or the one it's running to.
+	Pos        int
+	Setpoint   int
+	Speed      int
+	RampUp     time.Duration
+	RampDown   time.Duration
+	StopAction string
+	// Commands are the commands the motor got, in order.
+	Commands []string
+	// OnRun, if set, is called each time the motor runs to its setpoint.
+	OnRun func(from, to int)
+	// Stuck is the number of the next runs which get stuck halfway, e.g. at
+	// a jammed cube. A stuck motor is Stalled, unless HideStall is set.
+	Stuck     int
+	HideStall bool
+	// Lag is how far a run stops short of its setpoint, e.g. with the
+	// friction of the cube on the turntable.
+	Lag int
+	// Stops, if set, are the mechanical stops of the motor, which stall a
+	// run beyond them.
+	Stops *Stops
+	// Clock, if set, is the time in which the motor runs, at Speed and with
+	// its ramps.
+	Clock *Clock
+	// Err, if set, is returned by all the methods.
+	Err error
+
+	stalled bool
+	// origin is where the motor was last reset, from its first position.
+	origin int
+	// from and start are where and when the last run in the time of the
+	// clock started, and travel how long it takes.
+	from          int
+	start, travel time.Duration
+}
+
+// Clock is the time of motors which take time to move. Its Sleep passes the
+// time, for robot.Options.
+type Clock struct {
+	Now time.Duration
+}
+
+// Sleep passes the time d.
+func (c *Clock) Sleep(d time.Duration) {
+	c.Now += d
+}
+
+// Stops are the positions of the mechanical stops of a motor, in degrees from
+// its position before it's first reset.
+type Stops struct {
+	Min, Max int
+}
+
+func (m *Motor) Command(cmd string) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Commands = append(m.Commands, cmd)
+	// A command stops the last run where the motor got to.
+	if pos := m.position(); pos != m.Pos {
+		if m.OnRun != nil {
+			m.OnRun(m.Pos, pos)
+		}
+		m.Pos = pos
+	}
+	m.travel = 0
+	switch cmd {
+	case "reset":
+		m.origin += m.Pos
+		m.Pos, m.Setpoint, m.stalled = 0, 0, false
+	case "stop":
+		m.stalled = false
+	case "run-to-abs-pos":
+		from, to := m.Pos, m.Setpoint
+		m.stalled = m.Stuck > 0
+		if m.stalled {
+			m.Stuck--
+			to = from + (to-from)/2
+		} else if d := to - from; d > m.Lag {
+			to -= m.Lag
+		} else if d < -m.Lag {
+			to += m.Lag
+		} else {
+			to = from
+		}
+		if s := m.Stops; s != nil {
+			if to+m.origin < s.Min {
+				to, m.stalled = s.Min-m.origin, true
+			} else if to+m.origin > s.Max {
+				to, m.stalled = s.Max-m.origin, true
+			}
+		}
+		m.Pos = to
+		if m.Clock != nil && m.Speed > 0 {
+			m.from, m.start = from, m.Clock.Now
+			m.travel = time.Duration(abs(to-from))*time.Second/time.Duration(m.Speed) + (m.RampUp+m.RampDown)/2
+		}
+		if m.OnRun != nil {
+			m.OnRun(from, to)
+		}
+	}
+	return nil
+}
+
+// position returns where the motor is on its run.
+func (m *Motor) position() int {
+	if m.travel == 0 {
+		return m.Pos
+	}
+	elapsed := m.Clock.Now - m.start
+	if elapsed >= m.travel {
+		return m.Pos
+	}
+	return m.from + int(time.Duration(m.Pos-m.from)*elapsed/m.travel)
+}
+
+func (m *Motor) SetPositionSetpoint(pos int) error {
+	m.Setpoint = pos
+	return m.Err
+}
+
+func (m *Motor) SetSpeedSetpoint(speed int) error {
+	m.Speed = speed
+	return m.Err
+}
+
+func (m *Motor) SetRampUpSetpoint(d time.Duration) error {
+	m.RampUp = d
+	return m.Err
+}
+
+func (m *Motor) SetRampDownSetpoint(d time.Duration) error {
+	m.RampDown = d
+	return m.Err
+}
+
+func (m *Motor) SetStopAction(action string) error {
+	m.StopAction = action
+	return m.Err
+}
+
+func (m *Motor) Position() (int, error) {
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	return m.position(), nil
+}
+
+func (m *Motor) State() (robot.MotorState, error) {
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	if m.position() != m.Pos {
+		return robot.Running, nil
+	}
+	if m.stalled && !m.HideStall {
+		return robot.Stalled, nil
+	}
+	return robot.Holding, nil
+}
+
+// ColorSensor is a color sensor which reads R, G and B, or what Read returns
+// if set, or fails with Err if set.
+type ColorSensor struct {
+	R, G, B int
+	Read    func() (r, g, b int)
+	Err     error
+}
+
+func (s *ColorSensor) RGB() (r, g, b int, err error) {
+	if s.Err != nil {
+		return 0, 0, 0, s.Err
+	}
+	if s.Read != nil {
+		r, g, b = s.Read()
+		return r, g, b, nil
+	}
+	return s.R, s.G, s.B, nil
+}
+
+// palette are the readings of the color sensor over the stickers.
+var palette = map[cube.Color][3]int{
+	cube.White:  {200, 230, 240},
+	cube.Yellow: {190, 200, 60},
+	cube.Red:    {150, 30, 20},
+	cube.Orange: {210, 110, 30},
+	cube.Green:  {30, 140, 60},
+	cube.Blue:   {30, 70, 160},
+}
+
+// Sleep doesn't wait, for robot.Options.
+func Sleep(time.Duration) {}
+
+// Robot is the LEGO robot simulated over a cube. The flip arm rests against
+// its stop, tips the cube over when it passes 200 degrees, and holds the upper
+// layers between 90 and 200 degrees. The eye has a stop 250 degrees away, and
+// reads the top face at the positions of the default geometry: the center,
+// the front edge with the turntable square, and the front corners with it an
+// eighth off. It's over the cube beyond half way to the corners. 270 degrees
+// of the turntable turn the cube, or only its down face while the arm holds
+// the cube, by a quarter: +270 is a turn and -270 a D, as in package cube.
+type Robot struct {
+	Cube            *cube.Cube
+	Flip, Turn, Eye *Motor
+	Sensor          *ColorSensor
+	// Slack is how far in degrees of the turn motor the turntable can be off
+	// square for the flip arm to still move the cube, 10 by default.
+	Slack int
+	// Geometry is where the eye reads the cube.
+	Geometry robot.Geometry
+	// Errors are the motions which the real robot can't do, e.g. the flip
+	// arm moving while the turntable is between two faces.
+	Errors []error
+
+	held bool
+	// offset is how far the turn motor is from where the turntable was last
+	// square to the flip arm.
+	offset int
+	// reads is the number of readings of the color sensor, for their noise.
+	reads int
+}
+
+// NewRobot returns a simulated robot over the cube, which it moves.
+func NewRobot(c *cube.Cube) *Robot {
+	s := &Robot{Cube: c, Eye: &Motor{Stops: &Stops{Min: -800, Max: 250}}, Slack: 10, Geometry: robot.DefaultGeometry()}
+	s.Flip = &Motor{OnRun: s.flipRun, Stops: &Stops{Min: 0, Max: 300}}
+	s.Sensor = &ColorSensor{Read: s.look}
+	s.Turn = &Motor{OnRun: s.turnRun}
+	return s
+}
+
+// Timed makes the motors of the robot take time to move, in the time of a
+// clock they share, and returns the clock.
+func (s *Robot) Timed() *Clock {
+	c := &Clock{}
+	s.Flip.Clock, s.Turn.Clock, s.Eye.Clock = c, c, c
+	return c
+}
+
+// Hardware returns the motors and the sensor of the robot, for robot.New.
+func (s *Robot) Hardware() robot.Hardware {
+	return robot.Hardware{Flip: s.Flip, Turn: s.Turn, Eye: s.Eye, Sensor: s.Sensor}
+}
+
+// Aligned reports whether the turntable is square to the flip arm, within
+// Slack.
+func (s *Robot) Aligned() bool {
+	return abs(s.offset) <= s.Slack
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (s *Robot) flipRun(from, to int) {
+	if !s.Aligned() {
+		s.Errors = append(s.Errors, fmt.Errorf("the flip arm moved to %d with the turntable between two faces, at %d", to, s.Turn.Pos))
+	}
+	if eye := s.Eye.position(); eye < s.Geometry.Eye.Corner/2 {
+		s.Errors = append(s.Errors, fmt.Errorf("the flip arm moved to %d with the eye over the cube, at %d", to, eye))
+	}
+	if from < 200 && to >= 200 {
+		s.Cube.Do(cube.MoveFlip)
+	}
+	s.held = to >= 90 && to < 200
+}
+
+func (s *Robot) turnRun(from, to int) {
+	s.offset += to - from
+	quarters := s.offset / 270
+	if rem := s.offset % 270; rem > 135 {
+		quarters++
+	} else if rem < -135 {
+		quarters--
+	}
+	if abs(s.offset-quarters*270) > s.Slack {
+		return
+	}
+	s.offset -= quarters * 270
+	p := cube.MoveTurn1
+	if s.held {
+		p, quarters = cube.MoveD, -quarters
+	}
+	for i := 0; i < (quarters%4+4)%4; i++ {
+		s.Cube.Do(p)
+	}
+}
+
+// look reads the facelet under the eye, with some noise, or a dark reading if
+// the eye isn't over one.
+func (s *Robot) look() (r, g, b int) {
+	// The cube as the turntable turned it, within half a quarter.
+	c, offset := s.Cube.Clone(), s.offset
+	for ; offset > 135+s.Slack; offset -= 270 {
+		c.Do(cube.MoveTurn1)
+	}
+	for ; offset < -135-s.Slack; offset += 270 {
+		c.Do(cube.MoveRTurn)
+	}
+	near := func(a, b int) bool { return abs(a-b) <= s.Slack }
+	eye, pos := s.Geometry.Eye, s.Eye.position()
+	i := -1
+	switch {
+	case near(pos, eye.Center):
+		i = 4
+	case near(pos, eye.Edge) && near(offset, 0):
+		i = 7
+	case near(pos, eye.Corner) && near(offset, 135):
+		i = 8
+	case near(pos, eye.Corner) && near(offset, -135):
+		i = 6
+	}
+	if i < 0 {
+		return 5, 5, 5
+	}
+	s.reads++
+	noise := s.reads*37%21 - 10
+	rgb := palette[c.Face(cube.Up).Pieces[i]]
+	return rgb[0] + noise, rgb[1] - noise, rgb[2] + noise/2
+}
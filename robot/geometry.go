@@ -0,0 +1,150 @@
+// Geometry of the robot: the positions of the motors and the ports they're
+// plugged in, which differ from one LEGO build to another.
+//
+// A geometry profile is a versioned JSON file, e.g.:
+//
+//   {
+//     "version": 1,
+//     "name": "ross",
+//     "ports": {"flip": "A", "turn": "B", "eye": "C", "sensor": "4"},
+//     "flip": {"home": -360, "release": 5, "hold": 110, "flip": 220},
+//     "turn": {"quarter": 270, "tolerance": 5},
+//     "eye": {"home": 1000, "center": -710, "edge": -560, "corner": -520}
+//   }
+//
+// The fields left out keep the values of DefaultGeometry.
+//
+package robot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// GeometryVersion is the version of the geometry profiles this package reads
+// and writes.
+const GeometryVersion = 1
+
+// Geometry is the build of a robot. The positions are in degrees of the tacho
+// motors, from their zero after Connect.
+type Geometry struct {
+	Version int          `json:"version"`
+	Name    string       `json:"name,omitempty"`
+	Ports   Ports        `json:"ports"`
+	Flip    FlipGeometry `json:"flip"`
+	Turn    TurnGeometry `json:"turn"`
+	Eye     EyeGeometry  `json:"eye"`
+}
+
+// Ports are the output ports of the motors, A to D, and the input port of the
+// color sensor, 1 to 4.
+type Ports struct {
+	Flip   string `json:"flip"`
+	Turn   string `json:"turn"`
+	Eye    string `json:"eye"`
+	Sensor string `json:"sensor"`
+}
+
+// FlipGeometry are the positions of the flip arm: away from the cube, holding
+// its upper layers, and tipping it over. Its zero is its stop, found by
+// driving it back by Home degrees, at most.
+type FlipGeometry struct {
+	Home    int `json:"home"`
+	Release int `json:"release"`
+	Hold    int `json:"hold"`
+	Flip    int `json:"flip"`
+}
+
+// TurnGeometry is the gearing of the turntable: the rotation of the turn motor
+// which turns it by a quarter, and how far the motor may stop from a square
+// position before it's corrected.
+type TurnGeometry struct {
+	Quarter   int `json:"quarter"`
+	Tolerance int `json:"tolerance"`
+}
+
+// EyeGeometry are the positions of the arm of the color sensor over the
+// center, an edge and a corner of the top face. Its zero is its stop, found by
+// driving it by Home degrees, at most.
+type EyeGeometry struct {
+	Home   int `json:"home"`
+	Center int `json:"center"`
+	Edge   int `json:"edge"`
+	Corner int `json:"corner"`
+}
+
+// DefaultGeometry returns the geometry of the original robot.
+func DefaultGeometry() Geometry {
+	return Geometry{
+		Version: GeometryVersion,
+		Name:    "default",
+		Ports:   Ports{Flip: "A", Turn: "B", Eye: "C", Sensor: "4"},
+		Flip:    FlipGeometry{Home: -360, Release: 5, Hold: 110, Flip: 220},
+		Turn:    TurnGeometry{Quarter: 270, Tolerance: tolerance},
+		Eye:     EyeGeometry{Home: 1000, Center: -710, Edge: -560, Corner: -520},
+	}
+}
+
+// Validate checks that the geometry is one a robot can move with.
+func (g Geometry) Validate() error {
+	if g.Version != GeometryVersion {
+		return fmt.Errorf("unsupported geometry version %d, want %d", g.Version, GeometryVersion)
+	}
+	outputs := map[string]string{}
+	for _, p := range []struct{ motor, port string }{{"flip", g.Ports.Flip}, {"turn", g.Ports.Turn}, {"eye", g.Ports.Eye}} {
+		if len(p.port) != 1 || p.port[0] < 'A' || p.port[0] > 'D' {
+			return fmt.Errorf("invalid port %q of the %s motor, want A to D", p.port, p.motor)
+		}
+		if m, ok := outputs[p.port]; ok {
+			return fmt.Errorf("the %s and %s motors are both on port %s", m, p.motor, p.port)
+		}
+		outputs[p.port] = p.motor
+	}
+	if s := g.Ports.Sensor; len(s) != 1 || s[0] < '1' || s[0] > '4' {
+		return fmt.Errorf("invalid port %q of the color sensor, want 1 to 4", s)
+	}
+	if f := g.Flip; f.Home >= 0 || f.Release < 0 || f.Release+tolerance >= f.Hold || f.Hold+tolerance >= f.Flip {
+		return fmt.Errorf("invalid flip arm positions %+v, want home < 0 <= release < hold < flip", f)
+	}
+	if t := g.Turn; t.Quarter <= 0 || t.Tolerance <= 0 || t.Tolerance >= t.Quarter/8 {
+		return fmt.Errorf("invalid turntable %+v, want a positive quarter and a tolerance under 1/8 of it", t)
+	}
+	if e := g.Eye; e.Home <= 0 || e.Center >= e.Edge || e.Edge >= e.Corner || e.Corner >= 0 {
+		return fmt.Errorf("invalid eye positions %+v, want center < edge < corner < 0 < home", e)
+	}
+	return nil
+}
+
+// LoadGeometry reads a geometry profile from the file, over DefaultGeometry,
+// and validates it.
+func LoadGeometry(path string) (Geometry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Geometry{}, err
+	}
+	g := DefaultGeometry()
+	g.Version = 0
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&g); err != nil {
+		return Geometry{}, fmt.Errorf("invalid geometry profile %s: %v", path, err)
+	}
+	if err := g.Validate(); err != nil {
+		return Geometry{}, fmt.Errorf("invalid geometry profile %s: %v", path, err)
+	}
+	return g, nil
+}
+
+// Save writes the geometry profile to the file.
+func (g Geometry) Save(path string) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,68 @@
+// Homing of the motors against their mechanical stops.
+//
+// The flip arm and the eye have a mechanical stop at one end of their travel:
+// the flip arm lies back against the frame of the robot, and the arm of the
+// eye at the end of its rail. Each is driven gently towards its stop until it
+// stalls there, and zeroed, so that every run starts from the same physical
+// reference. The turntable turns freely and has no stop: it keeps its
+// orientation from the last run instead, see findSquare.
+//
+package robot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// homeSpeed is the speed of a motor driven towards its stop.
+	homeSpeed = 100
+	// homeTimeout is the time a motor is driven towards its stop.
+	homeTimeout = 15 * time.Second
+)
+
+// ErrNoStop is the error of a motor which didn't find its mechanical stop.
+var ErrNoStop = errors.New("no mechanical stop")
+
+// home drives the motor gently by travel degrees, or until it stalls against
+// its stop, zeroes it there, and sets it up for the motion.
+func (r *Robot) home(name string, motor Motor, travel int, motion Motion) error {
+	if err := r.setUp(name, motor, Motion{Speed: homeSpeed}); err != nil {
+		return fmt.Errorf("%s motor: can't set up: %v", name, err)
+	}
+	if err := motor.SetPositionSetpoint(travel); err != nil {
+		return fmt.Errorf("%s motor: can't set position %d: %v", name, travel, err)
+	}
+	if err := motor.Command("run-to-abs-pos"); err != nil {
+		return fmt.Errorf("%s motor: can't run to %d: %v", name, travel, err)
+	}
+	err := r.waitPosition(name, motor, travel, homeTimeout)
+	motor.Command("stop")
+	switch {
+	case err == nil:
+		return fmt.Errorf("%s motor ran %d degrees: %w", name, travel, ErrNoStop)
+	case !errors.Is(err, ErrStalled):
+		return err
+	}
+	r.debugf("%s motor homed.\n", name)
+	if err := r.setUp(name, motor, motion); err != nil {
+		return fmt.Errorf("%s motor: can't set up: %v", name, err)
+	}
+	return nil
+}
+
+// checkTravel runs the homed flip arm from its stop out to the flip and back,
+// to check that it travels freely. Each run tips the cube over, and four runs
+// leave it as it was.
+func (r *Robot) checkTravel() error {
+	for i := 0; i < 4; i++ {
+		for _, pos := range []int{r.geo.Flip.Flip, 0} {
+			if err := r.runTo("flip", r.flipMotor, pos); err != nil {
+				return fmt.Errorf("the flip arm can't travel to %d: %w", pos, err)
+			}
+			r.opts.Sleep(r.opts.Profile.Flip.Settle)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+// Motions of the motors, with stall detection and retries.
+//
+// A motion runs a motor to a position and polls it until it gets there. The
+// motor is stalled when ev3dev says so, or when it stops short of the target
+// for stallPolls polls, e.g. when the cube is jammed against the flip arm. A
+// failed flip, turn or hold backs the motor off to where it started, waits and
+// tries again, so that a misaligned cube gets a chance to settle.
+//
+// The turntable is closed-loop: after each turn, the position the turn motor
+// stopped at is measured against the square position of the turntable. The
+// motor is run back to square if it's off by more than its tolerance,
+// and how far it stopped short is added to the setpoint of the next turn, so
+// that backlash and slip don't build up over a long solve.
+//
+package robot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// pollInterval is the time between two polls of a moving motor.
+	pollInterval = 50 * time.Millisecond
+	// stallPolls is the number of polls without progress after which a
+	// motor is stalled.
+	stallPolls = 5
+	// tolerance is the distance in degrees from the target at which a
+	// motion is done.
+	tolerance = 5
+)
+
+var (
+	// ErrStalled is the error of a motion which stalled.
+	ErrStalled = errors.New("stalled")
+	// ErrTimeout is the error of a motion which didn't get to its target
+	// within the time of its travel and Options.Timeout.
+	ErrTimeout = errors.New("timed out")
+	// ErrMisaligned is the error of a turn which can't be brought back to a
+	// square position of the turntable.
+	ErrMisaligned = errors.New("misaligned")
+)
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// roundDiv returns n/d rounded to the nearest integer, for d > 0.
+func roundDiv(n, d int) int {
+	if n < 0 {
+		return -((-n + d/2) / d)
+	}
+	return (n + d/2) / d
+}
+
+// waitPosition polls the motor until it's at pos, and returns ErrStalled if
+// it stalls and ErrTimeout if it's still not there after the timeout.
+func (r *Robot) waitPosition(name string, motor Motor, pos int, timeout time.Duration) error {
+	cur, err := motor.Position()
+	if err != nil {
+		return fmt.Errorf("%s motor: can't get initial position: %v", name, err)
+	}
+	r.debugf("target=%d cur=%d", pos, cur)
+	still := 0
+	for waited := time.Duration(0); abs(pos-cur) > tolerance; waited += pollInterval {
+		if waited >= timeout {
+			r.debugf("timeout\n")
+			return fmt.Errorf("%s motor at %d of %d after %v: %w", name, cur, pos, waited, ErrTimeout)
+		}
+		state, err := motor.State()
+		if err != nil {
+			return fmt.Errorf("%s motor: can't get state: %v", name, err)
+		}
+		if state&(Stalled|Overloaded) != 0 {
+			r.debugf("stalled\n")
+			return fmt.Errorf("%s motor at %d of %d: %w", name, cur, pos, ErrStalled)
+		}
+		r.debugf("\rtarget=%d cur=%d   ", pos, cur)
+		r.opts.Sleep(pollInterval)
+		next, err := motor.Position()
+		if err != nil {
+			return fmt.Errorf("%s motor: can't get current position: %v", name, err)
+		}
+		if next == cur {
+			if still++; still >= stallPolls {
+				r.debugf("stalled\n")
+				return fmt.Errorf("%s motor stuck at %d of %d: %w", name, cur, pos, ErrStalled)
+			}
+		} else {
+			still = 0
+		}
+		cur = next
+	}
+	r.debugf("ok\n")
+	return nil
+}
+
+// travelTime returns the time a motor takes to run by dist degrees with the
+// motion, with its ramps.
+func travelTime(motion Motion, dist int) time.Duration {
+	t := motion.RampUp + motion.RampDown
+	if motion.Speed > 0 {
+		t += time.Duration(abs(dist)) * time.Second / time.Duration(motion.Speed)
+	}
+	return t
+}
+
+// runTo runs the motor to pos and waits until it's there, within the time of
+// its travel with the motion it's set up for, and Options.Timeout. A failed
+// motion stops the motor.
+func (r *Robot) runTo(name string, motor Motor, pos int) error {
+	cur, err := motor.Position()
+	if err != nil {
+		return fmt.Errorf("%s motor: can't get position: %v", name, err)
+	}
+	timeout := travelTime(r.motions[name], pos-cur) + r.opts.Timeout
+	if err := motor.SetPositionSetpoint(pos); err != nil {
+		return fmt.Errorf("%s motor: can't set position %d: %v", name, pos, err)
+	}
+	if err := motor.Command("run-to-abs-pos"); err != nil {
+		return fmt.Errorf("%s motor: can't run to %d: %v", name, pos, err)
+	}
+	if err := r.waitPosition(name, motor, pos, timeout); err != nil {
+		motor.Command("stop")
+		return err
+	}
+	return motor.Command("stop")
+}
+
+// retry runs the motion, and after each failure runs backOff and runs the
+// motion again, up to Options.Retries times with exponential backoff.
+func (r *Robot) retry(name string, motion, backOff func() error) error {
+	delay := r.opts.Backoff
+	err := motion()
+	for i := 0; err != nil && i < r.opts.Retries; i++ {
+		log.Printf("ERROR: %s failed, retrying in %v: %v", name, delay, err)
+		if err := backOff(); err != nil {
+			return fmt.Errorf("%s failed, and can't back off: %w", name, err)
+		}
+		r.opts.Sleep(delay)
+		delay *= 2
+		err = motion()
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed after %d retries: %w", name, r.opts.Retries, err)
+	}
+	return nil
+}
+
+// setMotion sets the motor up for the motion of a primitive.
+func (r *Robot) setMotion(name string, motor Motor, motion Motion) error {
+	if err := use(motor, motion); err != nil {
+		return fmt.Errorf("%s motor: can't set up the motion %+v: %v", name, motion, err)
+	}
+	r.motions[name] = motion
+	return nil
+}
+
+func (r *Robot) flip() error {
+	motion := r.opts.Profile.Flip
+	if err := r.setMotion("flip", r.flipMotor, motion); err != nil {
+		return err
+	}
+	return r.retry("flip", func() error {
+		if err := r.runTo("flip", r.flipMotor, r.geo.Flip.Flip); err != nil {
+			return err
+		}
+		r.opts.Sleep(motion.Settle)
+		return r.runTo("flip", r.flipMotor, r.geo.Flip.Release)
+	}, r.releaseCube)
+}
+
+// turn turns the whole cube by n quarters of the turntable.
+func (r *Robot) turn(n int) error {
+	return r.rotate(n, r.opts.Profile.Turn)
+}
+
+// rotate turns the turntable by n quarters with the motion, and squares it up.
+func (r *Robot) rotate(n int, motion Motion) error {
+	if err := r.setMotion("turn", r.turnMotor, motion); err != nil {
+		return err
+	}
+	quarters := r.quarters + n
+	square := quarters * r.geo.Turn.Quarter
+	err := r.retry("turn", func() error {
+		return r.runTo("turn", r.turnMotor, square+sign(n)*r.lag)
+	}, func() error {
+		return r.runTo("turn", r.turnMotor, r.turnPos)
+	})
+	if err != nil {
+		return err
+	}
+	r.opts.Sleep(motion.Settle)
+	r.quarters = quarters
+	return r.align(sign(n))
+}
+
+// align measures where the turn motor stopped after a turn in the direction
+// dir, learns how far it stopped short, and runs it back to the square
+// position of the turntable if it's off by more than its tolerance.
+func (r *Robot) align(dir int) error {
+	square := r.quarters * r.geo.Turn.Quarter
+	off, err := r.measureTurn(square)
+	if err != nil {
+		return err
+	}
+	r.learnLag(dir, off)
+	if abs(off) <= r.geo.Turn.Tolerance {
+		return nil
+	}
+	log.Printf("WARNING: turntable %d degrees off square, correcting", off)
+	dir = -sign(off)
+	if err := r.runTo("turn", r.turnMotor, square+dir*r.lag); err != nil {
+		return err
+	}
+	if off, err = r.measureTurn(square); err != nil {
+		return err
+	}
+	r.learnLag(dir, off)
+	if abs(off) > r.geo.Turn.Tolerance {
+		return fmt.Errorf("turn motor at %d, %d degrees off square: %w", r.turnPos, off, ErrMisaligned)
+	}
+	return nil
+}
+
+// measureTurn reads the position of the turn motor, and returns how far it is
+// from square.
+func (r *Robot) measureTurn(square int) (int, error) {
+	pos, err := r.turnMotor.Position()
+	if err != nil {
+		return 0, fmt.Errorf("turn motor: can't get position: %v", err)
+	}
+	r.turnPos = pos
+	return pos - square, nil
+}
+
+// learnLag adds how far a run of the turn motor in the direction dir stopped
+// off square to its lag, up to an eighth of a quarter.
+func (r *Robot) learnLag(dir, off int) {
+	maxLag := r.geo.Turn.Quarter / 8
+	r.lag -= dir * off
+	if r.lag > maxLag {
+		r.lag = maxLag
+	} else if r.lag < -maxLag {
+		r.lag = -maxLag
+	}
+}
+
+// findSquare takes the orientation of the turntable from the position of the
+// turn motor, which ev3dev keeps across runs until the motor is reset, and
+// squares it up.
+func (r *Robot) findSquare() error {
+	pos, err := r.turnMotor.Position()
+	if err != nil {
+		return fmt.Errorf("turn motor: can't get position: %v", err)
+	}
+	r.quarters, r.turnPos, r.lag = roundDiv(pos, r.geo.Turn.Quarter), pos, 0
+	return r.align(0)
+}
+
+// spin turns the solved cube round twice, back to the orientation the
+// turntable has at the zero of the turn motor.
+func (r *Robot) spin() error {
+	return r.turn(8 - (r.quarters%4+4)%4)
+}
+
+func (r *Robot) holdCube() error {
+	if err := r.setMotion("flip", r.flipMotor, r.opts.Profile.TurnDown); err != nil {
+		return err
+	}
+	return r.retry("hold", func() error {
+		return r.runTo("flip", r.flipMotor, r.geo.Flip.Hold)
+	}, r.releaseCube)
+}
+
+func (r *Robot) releaseCube() error {
+	return r.runTo("flip", r.flipMotor, r.geo.Flip.Release)
+}
+
+// turnDown holds the cube and turns its down face by n quarters of the turn
+// motor.
+func (r *Robot) turnDown(n int) error {
+	if err := r.holdCube(); err != nil {
+		return err
+	}
+	r.opts.Sleep(r.opts.Profile.TurnDown.Settle)
+	if err := r.rotate(n, r.opts.Profile.TurnDown); err != nil {
+		return err
+	}
+	return r.releaseCube()
+}
@@ -0,0 +1,95 @@
+// Motion profiles: how fast and how smoothly each primitive moves its motors.
+//
+// A profile sets the motion of each primitive: the flip, the turn of the whole
+// cube, the D move which holds the cube and turns its down face, and the sweep
+// of the eye. The profiles trade speed for reliability: "fast" to benchmark,
+// "safe" for everyday solves and "demo" to show the robot slowly. A motion
+// gets the time its travel takes at the speed of its profile, however slow.
+//
+package robot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Motion is how a motor moves in a primitive.
+type Motion struct {
+	// Speed is the speed setpoint of the motor, in degrees per second.
+	Speed int
+	// RampUp and RampDown are the times the motor takes to speed up from
+	// zero to full speed, and to slow down back to zero.
+	RampUp, RampDown time.Duration
+	// Settle is the wait after the motor gets to its position, for the cube
+	// to settle.
+	Settle time.Duration
+}
+
+// Profile is the motions of the primitives of the robot.
+type Profile struct {
+	Name string
+	// Flip is the motion of the flip arm when it flips the cube, Turn of the
+	// turntable when it turns the whole cube, TurnDown of both the flip arm
+	// and the turntable in a D move, and Eye of the arm of the color sensor.
+	Flip, Turn, TurnDown, Eye Motion
+}
+
+// DefaultProfile is the profile of a Robot without one.
+const DefaultProfile = "safe"
+
+// Profiles are the motion profiles by name.
+var Profiles = map[string]Profile{
+	"fast": {
+		Name:     "fast",
+		Flip:     Motion{Speed: 600, RampUp: 100 * time.Millisecond, RampDown: 100 * time.Millisecond, Settle: 100 * time.Millisecond},
+		Turn:     Motion{Speed: 700, RampUp: 150 * time.Millisecond, RampDown: 150 * time.Millisecond},
+		TurnDown: Motion{Speed: 500, RampUp: 200 * time.Millisecond, RampDown: 200 * time.Millisecond, Settle: 50 * time.Millisecond},
+		Eye:      Motion{Speed: 400},
+	},
+	"safe": {
+		Name:     "safe",
+		Flip:     Motion{Speed: 300, RampUp: 200 * time.Millisecond, RampDown: 200 * time.Millisecond, Settle: 200 * time.Millisecond},
+		Turn:     Motion{Speed: 300, RampUp: 200 * time.Millisecond, RampDown: 300 * time.Millisecond},
+		TurnDown: Motion{Speed: 250, RampUp: 300 * time.Millisecond, RampDown: 300 * time.Millisecond, Settle: 100 * time.Millisecond},
+		Eye:      Motion{Speed: 200},
+	},
+	"demo": {
+		Name:     "demo",
+		Flip:     Motion{Speed: 150, RampUp: 500 * time.Millisecond, RampDown: 500 * time.Millisecond, Settle: 500 * time.Millisecond},
+		Turn:     Motion{Speed: 150, RampUp: 500 * time.Millisecond, RampDown: 500 * time.Millisecond, Settle: 300 * time.Millisecond},
+		TurnDown: Motion{Speed: 120, RampUp: 500 * time.Millisecond, RampDown: 500 * time.Millisecond, Settle: 300 * time.Millisecond},
+		Eye:      Motion{Speed: 100, RampUp: 300 * time.Millisecond, RampDown: 300 * time.Millisecond},
+	},
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func ProfileNames() []string {
+	var names []string
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileFor returns the profile with the name.
+func ProfileFor(name string) (Profile, error) {
+	p, ok := Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown motion profile %q, want one of %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// use sets the motor up for the motion.
+func use(m Motor, motion Motion) error {
+	if err := m.SetSpeedSetpoint(motion.Speed); err != nil {
+		return err
+	}
+	if err := m.SetRampUpSetpoint(motion.RampUp); err != nil {
+		return err
+	}
+	return m.SetRampDownSetpoint(motion.RampDown)
+}
@@ -0,0 +1,297 @@
+// Package robot drives the LEGO EV3 robot which solves the cube.
+//
+// The robot has three tacho motors: the flip arm, which flips the whole cube
+// or holds its upper layers, the turntable under the cube, and the eye which
+// moves the color sensor over the cube. Its primitives are the physical moves
+// of package cube:
+//
+//   flip          the flip arm tips the cube over, the front face comes up
+//   turn, turn'   the turntable turns the whole cube
+//   D, D'         the flip arm holds the upper layers while the turntable
+//                 turns the down face
+//
+// The motors and the sensor are interfaces, implemented on the EV3 by package
+// ev3 and in memory by package fake, so that the robot can be tested without
+// an EV3:
+//
+//   r := robot.New(hw, robot.Options{Profile: robot.Profiles["safe"]})
+//   if err := r.Connect(); err != nil { ... }
+//   err := r.Solve([]string{"flip", "D", "turn'"})
+//
+package robot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+)
+
+// Motor is a tacho motor of the robot, running to absolute positions in
+// degrees.
+type Motor interface {
+	Command(cmd string) error
+	SetPositionSetpoint(pos int) error
+	SetSpeedSetpoint(speed int) error
+	SetStopAction(action string) error
+	SetRampUpSetpoint(d time.Duration) error
+	SetRampDownSetpoint(d time.Duration) error
+	Position() (int, error)
+	State() (MotorState, error)
+}
+
+// MotorState is the state of a motor, the flags of ev3dev.
+type MotorState uint
+
+const (
+	Running MotorState = 1 << iota
+	Ramping
+	Holding
+	Overloaded
+	Stalled
+)
+
+// ColorSensor is the color sensor of the eye, reading raw RGB values.
+type ColorSensor interface {
+	RGB() (r, g, b int, err error)
+}
+
+// Hardware are the motors and the sensor of the robot.
+type Hardware struct {
+	// Flip is the motor of the flip arm, Turn of the turntable and Eye of the
+	// arm of the color sensor.
+	Flip, Turn, Eye Motor
+	Sensor          ColorSensor
+}
+
+// Options configure a Robot.
+type Options struct {
+	// Geometry is the build of the robot, DefaultGeometry if it has no
+	// version.
+	Geometry Geometry
+	// Profile is the motion profile of the primitives, DefaultProfile if it
+	// has no name.
+	Profile Profile
+	// Timeout is the margin of each motion over the time it takes at the
+	// speed of its motion, with its ramps, 1.5s if zero.
+	Timeout time.Duration
+	// Retries is the number of retries of a failed flip, turn or hold. The
+	// first retry waits Backoff, 500ms if zero, and each next one twice as
+	// long.
+	Retries int
+	Backoff time.Duration
+	// StepByStep, if set, is read for a line before each move of Solve,
+	// e.g. os.Stdin to step through a solve.
+	StepByStep io.Reader
+	// Debug, if set, gets the progress of the motors, e.g. os.Stdout.
+	Debug io.Writer
+	// Sleep waits for the motors, time.Sleep if nil. A simulated robot can
+	// skip the waits.
+	Sleep func(time.Duration)
+}
+
+// Robot is the LEGO robot, see the package doc.
+type Robot struct {
+	flipMotor, turnMotor, eyeMotor Motor
+	sensor                         ColorSensor
+
+	opts Options
+	geo  Geometry
+	// quarters is the orientation of the turntable, in quarters from the zero
+	// of the turn motor, and turnPos the position the turn motor last stopped
+	// at.
+	quarters, turnPos int
+	// lag is how far the turn motor stops short of its setpoint, measured
+	// after each turn and added to the setpoint of the next one.
+	lag int
+	// motions are the motions the motors are set up for, by name, which time
+	// their runs.
+	motions map[string]Motion
+	moves   map[string]func() error
+}
+
+// New returns a robot with the hardware.
+func New(hw Hardware, opts Options) *Robot {
+	if opts.Profile.Name == "" {
+		opts.Profile = Profiles[DefaultProfile]
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 1500 * time.Millisecond
+	}
+	if opts.Geometry.Version == 0 {
+		opts.Geometry = DefaultGeometry()
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.Sleep == nil {
+		opts.Sleep = time.Sleep
+	}
+	r := &Robot{flipMotor: hw.Flip, turnMotor: hw.Turn, eyeMotor: hw.Eye, sensor: hw.Sensor, opts: opts, geo: opts.Geometry}
+	r.motions = map[string]Motion{}
+	r.initMoves()
+	return r
+}
+
+func (r *Robot) setUp(name string, m Motor, motion Motion) error {
+	if err := m.Command("reset"); err != nil {
+		return err
+	}
+	return r.hold(name, m, motion)
+}
+
+// hold sets the motor up for the motion, holding its position when it stops.
+func (r *Robot) hold(name string, m Motor, motion Motion) error {
+	if err := use(m, motion); err != nil {
+		return err
+	}
+	r.motions[name] = motion
+	return m.SetStopAction("hold")
+}
+
+// debugf writes the progress of the motors to Options.Debug, if set.
+func (r *Robot) debugf(format string, a ...interface{}) {
+	if r.opts.Debug != nil {
+		fmt.Fprintf(r.opts.Debug, format, a...)
+	}
+}
+
+// Connect homes the eye and the flip arm against their stops, sets up the
+// motions of the motors, and checks the travel of the flip arm. The eye is
+// homed first, out of the way of the flip arm. The turn motor isn't reset: the
+// turntable keeps its orientation from the last run, and is squared up before
+// the flip arm moves the cube.
+func (r *Robot) Connect() error {
+	if err := r.home("eye", r.eyeMotor, r.geo.Eye.Home, r.opts.Profile.Eye); err != nil {
+		return fmt.Errorf("can't home the eye: %w", err)
+	}
+	if err := r.home("flip", r.flipMotor, r.geo.Flip.Home, r.opts.Profile.Flip); err != nil {
+		return fmt.Errorf("can't home the flip arm: %w", err)
+	}
+	if err := r.hold("turn", r.turnMotor, r.opts.Profile.Turn); err != nil {
+		return fmt.Errorf("can't set up the turn motor: %v", err)
+	}
+	if err := r.findSquare(); err != nil {
+		return fmt.Errorf("can't square up the turntable: %v", err)
+	}
+	return r.checkTravel()
+}
+
+// Reset resets the motors, which stops them. The turn motor is only stopped,
+// so that it keeps the orientation of the turntable for the next run.
+func (r *Robot) Reset() {
+	r.flipMotor.Command("reset")
+	r.turnMotor.SetStopAction("coast")
+	r.turnMotor.Command("stop")
+	r.eyeMotor.Command("reset")
+}
+
+func (r *Robot) initMoves() {
+	r.moves = map[string]func() error{
+		cube.MoveFlip: func() error {
+			return r.flip()
+		},
+		cube.MoveTurn1: func() error {
+			return r.turn(1)
+		},
+		cube.MoveTurn2: func() error {
+			return r.turn(2)
+		},
+		cube.MoveRTurn: func() error {
+			return r.turn(-1)
+		},
+		cube.MoveD: func() error {
+			return r.turnDown(-1)
+		},
+		cube.MoveD2: func() error {
+			return r.turnDown(-2)
+		},
+		cube.Moved: func() error {
+			return r.turnDown(1)
+		},
+	}
+}
+
+// Move performs a physical move, one of the primitives of package cube.
+func (r *Robot) Move(m string) error {
+	op, ok := r.moves[m]
+	if !ok {
+		return fmt.Errorf("unknown move %q", m)
+	}
+	return op()
+}
+
+// Solve performs the physical moves of a solution, and spins the solved cube.
+// If a move fails after its retries, Solve releases the cube and stops.
+func (r *Robot) Solve(steps []string) error {
+	n := len(steps)
+	fmt.Printf("TOTAL STEPS=%d:\n", n)
+	var reader *bufio.Reader
+	if r.opts.StepByStep != nil {
+		reader = bufio.NewReader(r.opts.StepByStep)
+	}
+	for i, m := range steps {
+		fmt.Printf(">>> STEP %d/%d: %s\n", i, n, m)
+		if reader != nil {
+			reader.ReadString('\n')
+		}
+		if err := r.Move(m); err != nil {
+			return r.abort(fmt.Errorf("step %d/%d %s: %w", i, n, m, err))
+		}
+	}
+
+	if err := r.spin(); err != nil {
+		return r.abort(err)
+	}
+	return nil
+}
+
+// abort releases the cube after the error, so that it can be taken out of the
+// robot, and returns the error.
+func (r *Robot) abort(err error) error {
+	log.Printf("ERROR: aborting: %v", err)
+	if rerr := r.releaseCube(); rerr != nil {
+		log.Printf("ERROR: can't release the cube: %v", rerr)
+	}
+	r.flipMotor.Command("stop")
+	r.turnMotor.Command("stop")
+	return err
+}
+
+// SelfTest flips, turns and moves the down face back and forth, and reads the
+// color sensor, to check the hardware.
+func (r *Robot) SelfTest() error {
+	fmt.Printf("Flip two times.\n")
+	for _, m := range []string{cube.MoveFlip, cube.MoveFlip} {
+		if err := r.Move(m); err != nil {
+			return r.abort(err)
+		}
+	}
+
+	fmt.Printf("Action: turn reverseTurn turn2\n")
+	r.opts.Sleep(time.Second)
+	for _, m := range []string{cube.MoveTurn1, cube.MoveRTurn, cube.MoveTurn2} {
+		if err := r.Move(m); err != nil {
+			return r.abort(err)
+		}
+	}
+
+	fmt.Printf("Moves: D D' D2\n")
+	r.opts.Sleep(time.Second)
+	for _, m := range []string{cube.MoveD, cube.Moved, cube.MoveD2} {
+		if err := r.Move(m); err != nil {
+			return r.abort(err)
+		}
+	}
+
+	red, green, blue, err := r.sensor.RGB()
+	if err != nil {
+		return fmt.Errorf("can't read the color sensor: %v", err)
+	}
+	fmt.Printf("Color sensor: %d/%d/%d\n", red, green, blue)
+	fmt.Printf("\nDONE TEST\n")
+	return nil
+}
@@ -0,0 +1,710 @@
+package robot_test
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/robot"
+	"github.com/ross-wu/cube/robot/fake"
+)
+
+var testProfile = robot.Profile{
+	Name:     "test",
+	Flip:     robot.Motion{Speed: 300, RampUp: 100 * time.Millisecond, RampDown: 200 * time.Millisecond},
+	Turn:     robot.Motion{Speed: 400},
+	TurnDown: robot.Motion{Speed: 250, RampUp: 300 * time.Millisecond},
+	Eye:      robot.Motion{Speed: 200},
+}
+
+func newRobot(t *testing.T, c *cube.Cube) (*robot.Robot, *fake.Robot) {
+	sim := fake.NewRobot(c)
+	r := robot.New(sim.Hardware(), robot.Options{Profile: testProfile, Sleep: fake.Sleep})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	return r, sim
+}
+
+// near reports whether a motor at pos stopped at its setpoint, within the
+// tolerance of the robot.
+func near(pos, setpoint int) bool {
+	return pos >= setpoint-5 && pos <= setpoint+5
+}
+
+func checkErrors(t *testing.T, sim *fake.Robot) {
+	t.Helper()
+	for _, err := range sim.Errors {
+		t.Errorf("the robot can't move like this: %v", err)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	_, sim := newRobot(t, cube.SolvedCube())
+	for _, tc := range []struct {
+		name   string
+		motor  *fake.Motor
+		motion robot.Motion
+	}{
+		{"flip", sim.Flip, testProfile.Flip},
+		{"turn", sim.Turn, testProfile.Turn},
+		{"eye", sim.Eye, testProfile.Eye},
+	} {
+		got := robot.Motion{Speed: tc.motor.Speed, RampUp: tc.motor.RampUp, RampDown: tc.motor.RampDown}
+		if got != tc.motion || tc.motor.StopAction != "hold" {
+			t.Errorf("%s motor: motion %+v and stop action %q, want %+v and hold", tc.name, got, tc.motor.StopAction, tc.motion)
+		}
+		if tc.motor.Pos != 0 {
+			t.Errorf("%s motor at %d after Connect, want 0", tc.name, tc.motor.Pos)
+		}
+	}
+}
+
+func TestConnectError(t *testing.T) {
+	sim := fake.NewRobot(cube.SolvedCube())
+	sim.Eye.Err = errors.New("no such device")
+	r := robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep})
+	if err := r.Connect(); err == nil {
+		t.Errorf("Connect() with a broken eye motor succeeded")
+	}
+}
+
+// TestDebug checks that the progress of the motors goes to Options.Debug.
+func TestDebug(t *testing.T) {
+	var debug strings.Builder
+	sim := fake.NewRobot(cube.SolvedCube())
+	r := robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep, Debug: &debug})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	if err := r.Move(cube.MoveFlip); err != nil {
+		t.Fatalf("Move(flip) error: %v", err)
+	}
+	got := debug.String()
+	if !strings.Contains(got, "target=") || !strings.HasSuffix(got, "ok\n") {
+		t.Errorf("the progress of the motors is %q, want their targets", got)
+	}
+	for _, name := range []string{"eye", "flip"} {
+		if !strings.Contains(got, name+" motor homed.\n") {
+			t.Errorf("the progress of the motors is %q, want the %s motor homed", got, name)
+		}
+	}
+}
+
+// TestMove checks that each move of the robot is the primitive of package
+// cube.
+func TestMove(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, p := range []string{cube.MoveFlip, cube.MoveTurn1, cube.MoveTurn2, cube.MoveRTurn, cube.MoveD, cube.MoveD2, cube.Moved} {
+		c := cube.RandomCube(rnd)
+		want := c.Clone()
+		want.Do(p)
+		r, sim := newRobot(t, c)
+		if err := r.Move(p); err != nil {
+			t.Fatalf("Move(%s) error: %v", p, err)
+		}
+		checkErrors(t, sim)
+		if !reflect.DeepEqual(sim.Cube.Faces(), want.Faces()) {
+			t.Errorf("Move(%s) leaves the cube as %v, want %v", p, sim.Cube.Faces(), want.Faces())
+		}
+		if sim.Flip.Pos >= 90 {
+			t.Errorf("Move(%s) leaves the flip arm at %d, holding the cube", p, sim.Flip.Pos)
+		}
+	}
+}
+
+// TestSolve solves random scrambles with the physical moves of their inverse.
+func TestSolve(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 10; i++ {
+		var scramble []string
+		for j := 0; j < 20; j++ {
+			scramble = append(scramble, []string{"U", "L", "F", "R", "B", "D"}[rnd.Intn(6)]+[]string{"", "'", "2"}[rnd.Intn(3)])
+		}
+		c := cube.SolvedCube()
+		if _, err := c.Scramble(strings.Join(scramble, " ")); err != nil {
+			t.Fatalf("Scramble(%v) error: %v", scramble, err)
+		}
+		r, sim := newRobot(t, c.Clone())
+		steps, err := c.Apply(cube.InvertMoves(scramble))
+		if err != nil {
+			t.Fatalf("Apply(%v) error: %v", cube.InvertMoves(scramble), err)
+		}
+		if err := r.Solve(steps); err != nil {
+			t.Fatalf("Solve(%v) error: %v", steps, err)
+		}
+		checkErrors(t, sim)
+		if !sim.Cube.IsSolved() {
+			t.Errorf("Solve(%v) doesn't solve the scramble %v", steps, scramble)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	r, sim := newRobot(t, cube.SolvedCube())
+	if err := r.SelfTest(); err != nil {
+		t.Errorf("SelfTest() error: %v", err)
+	}
+	checkErrors(t, sim)
+
+	sim.Sensor.Err = errors.New("no such device")
+	if err := r.SelfTest(); err == nil {
+		t.Errorf("SelfTest() with a broken color sensor succeeded")
+	}
+}
+
+// TestRetry jams each motor once in each move, and checks that the robot
+// backs off and retries, and still moves the cube right.
+func TestRetry(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		for _, p := range []string{cube.MoveFlip, cube.MoveTurn1, cube.MoveD, cube.Moved} {
+			c := cube.SolvedCube()
+			want := c.Clone()
+			want.Do(p)
+			sim := fake.NewRobot(c)
+			r := robot.New(sim.Hardware(), robot.Options{Retries: 2, Sleep: fake.Sleep})
+			if err := r.Connect(); err != nil {
+				t.Fatalf("Connect() error: %v", err)
+			}
+			sim.Flip.Stuck, sim.Flip.HideStall = 1, hide
+			sim.Turn.Stuck, sim.Turn.HideStall = 1, hide
+			if err := r.Move(p); err != nil {
+				t.Fatalf("Move(%s) with jammed motors error: %v", p, err)
+			}
+			checkErrors(t, sim)
+			if !reflect.DeepEqual(sim.Cube.Faces(), want.Faces()) {
+				t.Errorf("Move(%s) with jammed motors leaves the cube as %v, want %v", p, sim.Cube.Faces(), want.Faces())
+			}
+		}
+	}
+}
+
+// TestAbort jams the turntable for good in the middle of a D, and checks
+// that Solve fails with ErrStalled as soon as the turntable can't back off,
+// and releases the cube.
+func TestAbort(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		sim := fake.NewRobot(cube.SolvedCube())
+		r := robot.New(sim.Hardware(), robot.Options{Retries: 2, Sleep: fake.Sleep})
+		if err := r.Connect(); err != nil {
+			t.Fatalf("Connect() error: %v", err)
+		}
+		sim.Turn.Stuck, sim.Turn.HideStall = 100, hide
+		err := r.Solve([]string{cube.MoveFlip, cube.MoveD, cube.MoveFlip})
+		if !errors.Is(err, robot.ErrStalled) {
+			t.Errorf("Solve() with a jammed turntable error: %v, want %v", err, robot.ErrStalled)
+		}
+		if sim.Flip.Pos >= 90 {
+			t.Errorf("Solve() aborted with the flip arm at %d, holding the cube", sim.Flip.Pos)
+		}
+		if n := 100 - sim.Turn.Stuck; n != 2 {
+			t.Errorf("the turntable ran %d times, want 2: the turn and the back off", n)
+		}
+	}
+}
+
+// TestTimeout checks that a motor which keeps moving but never gets to its
+// target times out after the time of its travel and Options.Timeout.
+func TestTimeout(t *testing.T) {
+	sim := fake.NewRobot(cube.SolvedCube())
+	slept := time.Duration(0)
+	r := robot.New(sim.Hardware(), robot.Options{Timeout: time.Second, Sleep: func(d time.Duration) {
+		slept += d
+		// The arm creeps towards its target, but never gets there.
+		sim.Flip.Pos++
+	}})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	slept = 0
+	sim.Flip.Stuck, sim.Flip.HideStall = 1, true
+	if err := r.Move(cube.MoveFlip); !errors.Is(err, robot.ErrTimeout) {
+		t.Errorf("Move(flip) with a slow arm error: %v, want %v", err, robot.ErrTimeout)
+	}
+	// 220 degrees at 300 degrees/s, with the ramps of the safe profile.
+	want := time.Second + 220*time.Second/300 + 400*time.Millisecond
+	if slept < want || slept > want+100*time.Millisecond {
+		t.Errorf("Move(flip) with a slow arm waited %v, want about %v", slept, want)
+	}
+}
+
+// TestTravel solves a scramble with motors which take time to move, and checks
+// that the long moves get the time of their travel: a turn2 and a D2 run 540
+// degrees, and the spin of the solved cube up to 2160.
+func TestTravel(t *testing.T) {
+	c := cube.SolvedCube()
+	scramble := []string{"R2", "U", "F2", "D2", "L'", "B2", "U'", "R", "D", "F2"}
+	if _, err := c.Scramble(strings.Join(scramble, " ")); err != nil {
+		t.Fatalf("Scramble(%v) error: %v", scramble, err)
+	}
+	sim := fake.NewRobot(c.Clone())
+	clock := sim.Timed()
+	r := robot.New(sim.Hardware(), robot.Options{Sleep: clock.Sleep})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	steps, err := c.Apply(cube.InvertMoves(scramble))
+	if err != nil {
+		t.Fatalf("Apply(%v) error: %v", cube.InvertMoves(scramble), err)
+	}
+	start := clock.Now
+	if err := r.Solve(steps); err != nil {
+		t.Fatalf("Solve(%v) with slow motors error: %v", steps, err)
+	}
+	if !sim.Cube.IsSolved() {
+		t.Errorf("Solve(%v) with slow motors doesn't solve the scramble %v", steps, scramble)
+	}
+	want := sim.Cube.Clone()
+	for _, m := range []string{cube.MoveTurn2, cube.MoveD2} {
+		if err := r.Move(m); err != nil {
+			t.Fatalf("Move(%s) with slow motors error: %v", m, err)
+		}
+	}
+	checkErrors(t, sim)
+	want.Do(cube.MoveTurn2)
+	want.Do(cube.MoveD2)
+	if !reflect.DeepEqual(sim.Cube.Faces(), want.Faces()) {
+		t.Errorf("turn2 and D2 with slow motors leave the cube as %v, want %v", sim.Cube.Faces(), want.Faces())
+	}
+	if clock.Now-start < time.Duration(len(steps))*time.Second {
+		t.Errorf("Solve() of %d steps took %v, the motors didn't take time to move", len(steps), clock.Now-start)
+	}
+}
+
+// TestRetries jams the turntable on each try of a turn, but lets it back off,
+// and checks that the turn fails after its retries, with exponential backoff.
+func TestRetries(t *testing.T) {
+	sim := fake.NewRobot(cube.SolvedCube())
+	var delays []time.Duration
+	r := robot.New(sim.Hardware(), robot.Options{Retries: 3, Backoff: 100 * time.Millisecond, Sleep: func(d time.Duration) {
+		delays = append(delays, d)
+		// Jam the next try.
+		sim.Turn.Stuck = 1
+	}})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	delays = nil
+	sim.Turn.Stuck = 1
+	if err := r.Move(cube.MoveTurn1); !errors.Is(err, robot.ErrStalled) {
+		t.Errorf("Move(turn) with a jammed turntable error: %v, want %v", err, robot.ErrStalled)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	if !reflect.DeepEqual(delays, want) {
+		t.Errorf("Move(turn) with a jammed turntable waited %v, want %v", delays, want)
+	}
+	checkErrors(t, sim)
+	if !sim.Cube.IsSolved() {
+		t.Errorf("Move(turn) with a jammed turntable moved the cube")
+	}
+}
+
+// TestDrift solves a scramble with a turntable which stops short of each
+// setpoint by more than the cube's slack, and checks that the robot squares it
+// up and corrects its setpoints.
+func TestDrift(t *testing.T) {
+	c := cube.SolvedCube()
+	scramble := []string{"R", "U'", "F2", "D", "L", "B'", "U2", "R'", "D'", "F"}
+	if _, err := c.Scramble(strings.Join(scramble, " ")); err != nil {
+		t.Fatalf("Scramble(%v) error: %v", scramble, err)
+	}
+	sim := fake.NewRobot(c.Clone())
+	sim.Turn.Lag, sim.Slack = 4, 2
+	geo := robot.DefaultGeometry()
+	geo.Turn.Tolerance = 2
+	r := robot.New(sim.Hardware(), robot.Options{Geometry: geo, Sleep: fake.Sleep})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	steps, err := c.Apply(cube.InvertMoves(scramble))
+	if err != nil {
+		t.Fatalf("Apply(%v) error: %v", cube.InvertMoves(scramble), err)
+	}
+	if err := r.Solve(steps); err != nil {
+		t.Fatalf("Solve(%v) error: %v", steps, err)
+	}
+	checkErrors(t, sim)
+	if !sim.Cube.IsSolved() {
+		t.Errorf("Solve(%v) with a lagging turntable doesn't solve the scramble %v", steps, scramble)
+	}
+	// Only the first turn is corrected, the next ones aim past their square
+	// position by the lag measured.
+	runs := 0
+	for _, cmd := range sim.Turn.Commands {
+		if cmd == "run-to-abs-pos" {
+			runs++
+		}
+	}
+	turns := 1
+	for _, m := range steps {
+		if m != cube.MoveFlip {
+			turns++
+		}
+	}
+	if runs != turns+1 {
+		t.Errorf("the turntable ran %d times for %d turns, want one correction", runs, turns)
+	}
+}
+
+// TestOrientation checks that the turntable keeps its orientation from one run
+// to the next.
+func TestOrientation(t *testing.T) {
+	r, sim := newRobot(t, cube.SolvedCube())
+	if err := r.Move(cube.MoveTurn1); err != nil {
+		t.Fatalf("Move(turn) error: %v", err)
+	}
+	r.Reset()
+
+	r = robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	if sim.Turn.Pos != 270 {
+		t.Errorf("turn motor at %d after the next Connect, want 270", sim.Turn.Pos)
+	}
+	if err := r.Move(cube.MoveTurn1); err != nil {
+		t.Fatalf("Move(turn) error: %v", err)
+	}
+	checkErrors(t, sim)
+	if sim.Turn.Pos != 540 {
+		t.Errorf("turn motor at %d after two turns, want 540", sim.Turn.Pos)
+	}
+	want := cube.SolvedCube()
+	want.Do(cube.MoveTurn2)
+	if !reflect.DeepEqual(sim.Cube.Faces(), want.Faces()) {
+		t.Errorf("two turns leave the cube as %v, want %v", sim.Cube.Faces(), want.Faces())
+	}
+}
+
+// TestProfile checks that each primitive moves with its motion and settles.
+func TestProfile(t *testing.T) {
+	for _, tc := range []struct {
+		move       string
+		flip, turn robot.Motion
+		settle     time.Duration
+	}{
+		{cube.MoveFlip, testProfile.Flip, testProfile.Turn, 400 * time.Millisecond},
+		{cube.MoveTurn1, testProfile.Flip, testProfile.Turn, 500 * time.Millisecond},
+		{cube.MoveD, testProfile.TurnDown, testProfile.TurnDown, 600 * time.Millisecond},
+	} {
+		prof := testProfile
+		prof.Flip.Settle, prof.Turn.Settle, prof.TurnDown.Settle = 400*time.Millisecond, 500*time.Millisecond, 300*time.Millisecond
+		sim := fake.NewRobot(cube.SolvedCube())
+		var settled time.Duration
+		r := robot.New(sim.Hardware(), robot.Options{Profile: prof, Sleep: func(d time.Duration) { settled += d }})
+		if err := r.Connect(); err != nil {
+			t.Fatalf("Connect() error: %v", err)
+		}
+		settled = 0
+		if err := r.Move(tc.move); err != nil {
+			t.Fatalf("Move(%s) error: %v", tc.move, err)
+		}
+		for _, m := range []struct {
+			name  string
+			motor *fake.Motor
+			want  robot.Motion
+		}{{"flip", sim.Flip, tc.flip}, {"turn", sim.Turn, tc.turn}} {
+			got := robot.Motion{Speed: m.motor.Speed, RampUp: m.motor.RampUp, RampDown: m.motor.RampDown}
+			if got != m.want {
+				t.Errorf("Move(%s): %s motor motion %+v, want %+v", tc.move, m.name, got, m.want)
+			}
+		}
+		if settled != tc.settle {
+			t.Errorf("Move(%s) settled for %v, want %v", tc.move, settled, tc.settle)
+		}
+	}
+}
+
+// TestProfiles solves a scramble and scans the cube with each profile, with
+// motors which take time to move.
+func TestProfiles(t *testing.T) {
+	scramble := []string{"F2", "U'", "R2", "D", "B2", "L", "U2", "F'", "D2", "R'"}
+	for _, name := range robot.ProfileNames() {
+		c := cube.SolvedCube()
+		if _, err := c.Scramble(strings.Join(scramble, " ")); err != nil {
+			t.Fatalf("Scramble(%v) error: %v", scramble, err)
+		}
+		sim := fake.NewRobot(c.Clone())
+		clock := sim.Timed()
+		r := robot.New(sim.Hardware(), robot.Options{Profile: robot.Profiles[name], Sleep: clock.Sleep})
+		if err := r.Connect(); err != nil {
+			t.Fatalf("%s: Connect() error: %v", name, err)
+		}
+		steps, err := c.Apply(cube.InvertMoves(scramble))
+		if err != nil {
+			t.Fatalf("Apply(%v) error: %v", cube.InvertMoves(scramble), err)
+		}
+		if err := r.Solve(steps); err != nil {
+			t.Fatalf("%s: Solve(%v) error: %v", name, steps, err)
+		}
+		if !sim.Cube.IsSolved() {
+			t.Errorf("%s: Solve(%v) doesn't solve the scramble %v", name, steps, scramble)
+		}
+		scanned, err := r.Scan()
+		if err != nil {
+			t.Fatalf("%s: Scan() error: %v", name, err)
+		}
+		if !reflect.DeepEqual(scanned.Faces(), sim.Cube.Faces()) {
+			t.Errorf("%s: Scan() = %v, want %v", name, scanned.Faces(), sim.Cube.Faces())
+		}
+		checkErrors(t, sim)
+	}
+}
+
+func TestProfileFor(t *testing.T) {
+	for _, name := range robot.ProfileNames() {
+		p, err := robot.ProfileFor(name)
+		if err != nil || p.Name != name {
+			t.Errorf("ProfileFor(%q) = %q, %v", name, p.Name, err)
+		}
+	}
+	if _, err := robot.ProfileFor("turbo"); err == nil {
+		t.Errorf("ProfileFor(turbo) succeeded")
+	}
+}
+
+func TestLoadGeometry(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name, json string
+		want       func(*robot.Geometry)
+		err        string
+	}{
+		{"default", `{"version": 1}`, func(*robot.Geometry) {}, ""},
+		{"partial", `{"version": 1, "name": "mine", "flip": {"release": 0, "hold": 100, "flip": 210}, "ports": {"flip": "D", "turn": "B", "eye": "C", "sensor": "1"}}`, func(g *robot.Geometry) {
+			g.Name = "mine"
+			g.Flip.Release, g.Flip.Hold, g.Flip.Flip = 0, 100, 210
+			g.Ports.Flip, g.Ports.Sensor = "D", "1"
+		}, ""},
+		{"no version", `{"name": "mine"}`, nil, "unsupported geometry version 0"},
+		{"new version", `{"version": 2}`, nil, "unsupported geometry version 2"},
+		{"typo", `{"version": 1, "turn": {"quater": 360}}`, nil, "unknown field"},
+		{"flip", `{"version": 1, "flip": {"release": 5, "hold": 250, "flip": 220}}`, nil, "invalid flip arm positions"},
+		{"flip home", `{"version": 1, "flip": {"home": 100}}`, nil, "invalid flip arm positions"},
+		{"ports", `{"version": 1, "ports": {"flip": "A", "turn": "A", "eye": "C", "sensor": "4"}}`, nil, "both on port A"},
+		{"sensor", `{"version": 1, "ports": {"flip": "A", "turn": "B", "eye": "C", "sensor": "5"}}`, nil, "color sensor"},
+		{"eye", `{"version": 1, "eye": {"home": 1000, "center": -500, "edge": -560, "corner": -520}}`, nil, "invalid eye positions"},
+	} {
+		path := filepath.Join(dir, tc.name+".json")
+		if err := os.WriteFile(path, []byte(tc.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		g, err := robot.LoadGeometry(path)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("LoadGeometry(%s) error: %v, want %q", tc.json, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LoadGeometry(%s) error: %v", tc.json, err)
+			continue
+		}
+		want := robot.DefaultGeometry()
+		tc.want(&want)
+		if g != want {
+			t.Errorf("LoadGeometry(%s) = %+v, want %+v", tc.json, g, want)
+		}
+	}
+}
+
+func TestSaveGeometry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robot.json")
+	want := robot.DefaultGeometry()
+	want.Name, want.Turn.Quarter, want.Eye.Corner = "mine", 360, -500
+	if err := want.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	got, err := robot.LoadGeometry(path)
+	if err != nil {
+		t.Fatalf("LoadGeometry() error: %v", err)
+	}
+	if got != want {
+		t.Errorf("LoadGeometry() of the saved geometry = %+v, want %+v", got, want)
+	}
+
+	want.Version = 0
+	if err := want.Save(path); err == nil {
+		t.Errorf("Save() of an invalid geometry succeeded")
+	}
+}
+
+// TestGeometry checks that the robot moves the flip arm to the positions of
+// its geometry.
+func TestGeometry(t *testing.T) {
+	sim := fake.NewRobot(cube.SolvedCube())
+	geo := robot.DefaultGeometry()
+	geo.Flip.Release, geo.Flip.Hold, geo.Flip.Flip = 10, 120, 240
+	r := robot.New(sim.Hardware(), robot.Options{Geometry: geo, Sleep: fake.Sleep})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	var positions []int
+	run := sim.Flip.OnRun
+	sim.Flip.OnRun = func(from, to int) {
+		positions = append(positions, to)
+		run(from, to)
+	}
+	for _, m := range []string{cube.MoveFlip, cube.MoveD} {
+		if err := r.Move(m); err != nil {
+			t.Fatalf("Move(%s) error: %v", m, err)
+		}
+	}
+	checkErrors(t, sim)
+	if want := []int{240, 10, 120, 10}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("the flip arm ran to %v, want %v", positions, want)
+	}
+}
+
+// TestCalibrate calibrates the robot with motors which run at once, and with
+// ones which take time to move: the eye takes seconds from its stop to the
+// center of the cube.
+func TestCalibrate(t *testing.T) {
+	for _, timed := range []bool{false, true} {
+		sim := fake.NewRobot(cube.SolvedCube())
+		sleep := fake.Sleep
+		if timed {
+			sleep = sim.Timed().Sleep
+		}
+		r := robot.New(sim.Hardware(), robot.Options{Sleep: sleep})
+		if err := r.Connect(); err != nil {
+			t.Fatalf("Connect() error: %v", err)
+		}
+		input := strings.Join([]string{
+			// Release: kept.
+			"", "y",
+			// Hold: jogged, rejected, and jogged back a bit.
+			"+", "", "n", "-5", "", "y",
+			// Flip: kept.
+			"", "",
+			// A full turn of the turntable, 20 degrees further.
+			"+20", "", "y",
+			// Center, edge and corner of the eye.
+			"-10", "", "y",
+			"", "y",
+			"+", "", "y",
+		}, "\n") + "\n"
+		geo, err := r.Calibrate(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("timed %v: Calibrate() error: %v", timed, err)
+		}
+		checkErrors(t, sim)
+		want := robot.DefaultGeometry()
+		want.Flip.Hold = 115
+		want.Turn.Quarter = 275
+		want.Eye.Center, want.Eye.Corner = -720, -510
+		if geo != want {
+			t.Errorf("timed %v: Calibrate() = %+v, want %+v", timed, geo, want)
+		}
+		// The motors stop within 5 degrees of their setpoints.
+		if !near(sim.Flip.Pos, 5) || !near(sim.Turn.Pos, 0) || !near(sim.Eye.Pos, 0) {
+			t.Errorf("timed %v: Calibrate() leaves the motors at %d, %d and %d, want 5, 0 and 0", timed, sim.Flip.Pos, sim.Turn.Pos, sim.Eye.Pos)
+		}
+
+		// The robot moves with the new geometry.
+		if err := r.Move(cube.MoveTurn1); err != nil {
+			t.Fatalf("timed %v: Move(turn) error: %v", timed, err)
+		}
+		if !near(sim.Turn.Pos, 275) {
+			t.Errorf("timed %v: turn motor at %d after a turn, want 275", timed, sim.Turn.Pos)
+		}
+	}
+}
+
+func TestCalibrateCanceled(t *testing.T) {
+	for _, input := range []string{"+\nq\n", "+\n+\n"} {
+		r, _ := newRobot(t, cube.SolvedCube())
+		if _, err := r.Calibrate(strings.NewReader(input)); !errors.Is(err, robot.ErrCanceled) {
+			t.Errorf("Calibrate(%q) error: %v, want %v", input, err, robot.ErrCanceled)
+		}
+	}
+}
+
+// TestHome checks that Connect homes the flip arm and the eye against their
+// stops, wherever they are.
+func TestHome(t *testing.T) {
+	sim := fake.NewRobot(cube.SolvedCube())
+	sim.Flip.Pos, sim.Eye.Pos = 60, -300
+	r := robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep})
+	if err := r.Connect(); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	checkErrors(t, sim)
+	if sim.Flip.Pos != 0 || sim.Eye.Pos != 0 {
+		t.Errorf("Connect() leaves the flip arm at %d and the eye at %d, want 0 at their stops", sim.Flip.Pos, sim.Eye.Pos)
+	}
+	// The check of the travel of the flip arm leaves the cube as it was.
+	if !reflect.DeepEqual(sim.Cube.Faces(), cube.SolvedCube().Faces()) {
+		t.Errorf("Connect() leaves the cube as %v, want it as it was", sim.Cube.Faces())
+	}
+	// The zeroes are at the stops.
+	if err := r.Move(cube.MoveFlip); err != nil {
+		t.Fatalf("Move(flip) error: %v", err)
+	}
+	want := cube.SolvedCube()
+	want.Do(cube.MoveFlip)
+	if !reflect.DeepEqual(sim.Cube.Faces(), want.Faces()) {
+		t.Errorf("Move(flip) after homing leaves the cube as %v, want %v", sim.Cube.Faces(), want.Faces())
+	}
+}
+
+func TestHomeError(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(*fake.Robot)
+		err   error
+	}{
+		{"no stop", func(sim *fake.Robot) { sim.Eye.Stops = nil }, robot.ErrNoStop},
+		{"short travel", func(sim *fake.Robot) { sim.Flip.Stops.Max = 50 }, robot.ErrStalled},
+		{"short of the flip", func(sim *fake.Robot) { sim.Flip.Stops.Max = 150 }, robot.ErrStalled},
+	} {
+		sim := fake.NewRobot(cube.SolvedCube())
+		tc.setup(sim)
+		r := robot.New(sim.Hardware(), robot.Options{Sleep: fake.Sleep})
+		err := r.Connect()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: Connect() error: %v, want %v", tc.name, err, tc.err)
+		}
+		if tc.err == robot.ErrStalled && !strings.Contains(err.Error(), "travel to 220") {
+			t.Errorf("%s: Connect() error: %v, want the flip arm short of 220", tc.name, err)
+		}
+	}
+}
+
+// TestScan scans random cubes on the simulated robot, and checks that the
+// colors read are the ones of the cube as the scan leaves it.
+func TestScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	for i := 0; i < 5; i++ {
+		r, sim := newRobot(t, cube.RandomCube(rnd))
+		sim.Turn.Lag = i
+		c, err := r.Scan()
+		if err != nil {
+			t.Fatalf("Scan() error: %v", err)
+		}
+		checkErrors(t, sim)
+		if !reflect.DeepEqual(c.Faces(), sim.Cube.Faces()) {
+			t.Errorf("Scan() = %v, want %v", c.Faces(), sim.Cube.Faces())
+		}
+		if err := c.Validate(); err != nil {
+			t.Errorf("Scan() = %v, invalid: %v", c.Faces(), err)
+		}
+		if sim.Eye.Pos != 0 || !sim.Aligned() {
+			t.Errorf("Scan() leaves the eye at %d and the turntable %d degrees off", sim.Eye.Pos, sim.Turn.Pos%270)
+		}
+	}
+}
+
+func TestScanError(t *testing.T) {
+	r, sim := newRobot(t, cube.SolvedCube())
+	sim.Sensor.Err = errors.New("no such device")
+	if _, err := r.Scan(); err == nil {
+		t.Errorf("Scan() with a broken color sensor succeeded")
+	}
+}
@@ -0,0 +1,240 @@
+// Scanning of the cube with the color sensor.
+//
+// The eye reaches over the front of the top face. It reads the center, then
+// the front edge with the turntable square, turning it a quarter at a time,
+// and the front left corner with the turntable an eighth back from square.
+// The cube is flipped and turned until each face has been on top.
+//
+// The moves are tracked on a cube whose "colors" are the indexes of the
+// facelets as they were when the scan started: the facelet under the eye is
+// the one of that cube at the same position, and its faces at the end map the
+// readings to the cube as it's left on the turntable.
+//
+package robot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ross-wu/cube/cube"
+)
+
+// RGB is a raw reading of the color sensor.
+type RGB struct {
+	R, G, B int
+}
+
+// referenceColors are typical raw readings of the color sensor over the
+// stickers, which name the colors of the centers.
+var referenceColors = map[cube.Color]RGB{
+	cube.White:  {195, 236, 237},
+	cube.Yellow: {180, 200, 50},
+	cube.Red:    {107, 40, 26},
+	cube.Orange: {160, 90, 30},
+	cube.Green:  {30, 120, 60},
+	cube.Blue:   {27, 91, 134},
+}
+
+// scanFaces are the face codes in the order of the facelet indexes.
+var scanFaces = []byte{cube.Up, cube.Left, cube.Front, cube.Right, cube.Back, cube.Down}
+
+// Scan reads the colors of the cube on the turntable, and returns it as it's
+// left at the end of the scan. The cube it returns isn't validated.
+func (r *Robot) Scan() (*cube.Cube, error) {
+	labels := cube.NewCube()
+	for i, code := range scanFaces {
+		face := &cube.Face{}
+		for j := range face.Pieces {
+			face.Pieces[j] = cube.Color(i*9 + j)
+		}
+		labels.SetFace(code, face)
+	}
+	var readings [54]RGB
+	scanned := map[cube.Color]bool{}
+	for n := 0; n < len(scanFaces); n++ {
+		if n > 0 {
+			for _, m := range nextFace(labels, scanned) {
+				if err := r.Move(m); err != nil {
+					return nil, r.abort(fmt.Errorf("scan: %w", err))
+				}
+				labels.Do(m)
+			}
+		}
+		fmt.Printf("Scanning face %d/%d.\n", n+1, len(scanFaces))
+		if err := r.scanFace(labels, &readings); err != nil {
+			return nil, r.abort(fmt.Errorf("scan: %w", err))
+		}
+		scanned[labels.Face(cube.Up).Pieces[4]] = true
+	}
+
+	colors := classify(readings)
+	c := cube.NewCube()
+	for _, code := range scanFaces {
+		face := &cube.Face{}
+		for j, label := range labels.Face(code).Pieces {
+			face.Pieces[j] = colors[label]
+		}
+		c.SetFace(code, face)
+	}
+	return c, nil
+}
+
+// scanFace reads the top face into the readings of its labels, and leaves the
+// turntable as it found it, with the eye back at its stop.
+func (r *Robot) scanFace(labels *cube.Cube, readings *[54]RGB) error {
+	eye, quarter := r.geo.Eye, r.geo.Turn.Quarter
+	read := func(i int) error {
+		r.opts.Sleep(r.opts.Profile.Eye.Settle)
+		red, green, blue, err := r.sensor.RGB()
+		if err != nil {
+			return fmt.Errorf("can't read the color sensor: %v", err)
+		}
+		readings[labels.Face(cube.Up).Pieces[i]] = RGB{red, green, blue}
+		return nil
+	}
+
+	if err := r.runTo("eye", r.eyeMotor, eye.Center); err != nil {
+		return err
+	}
+	if err := read(4); err != nil {
+		return err
+	}
+	if err := r.runTo("eye", r.eyeMotor, eye.Edge); err != nil {
+		return err
+	}
+	for k := 0; k < 4; k++ {
+		if k > 0 {
+			if err := r.turn(1); err != nil {
+				return err
+			}
+			labels.Do(cube.MoveTurn1)
+		}
+		if err := read(7); err != nil {
+			return err
+		}
+	}
+	if err := r.runTo("eye", r.eyeMotor, eye.Corner); err != nil {
+		return err
+	}
+	for k := 0; k < 4; k++ {
+		if k > 0 {
+			labels.Do(cube.MoveRTurn)
+		}
+		pos := (r.quarters-k)*quarter - quarter/2
+		if err := r.runTo("turn", r.turnMotor, pos); err != nil {
+			return err
+		}
+		r.turnPos = pos
+		if err := read(6); err != nil {
+			return err
+		}
+	}
+	// The labels are back where the face started, and the turntable half a
+	// quarter before: square it up a quarter from the one before.
+	r.quarters -= 4
+	if err := r.turn(1); err != nil {
+		return err
+	}
+	return r.runTo("eye", r.eyeMotor, 0)
+}
+
+// nextFace returns the shortest moves which bring a face which wasn't scanned
+// on top.
+func nextFace(labels *cube.Cube, scanned map[cube.Color]bool) []string {
+	primitives := []string{cube.MoveFlip, cube.MoveTurn1, cube.MoveRTurn, cube.MoveTurn2}
+	seqs := [][]string{nil}
+	for len(seqs) > 0 {
+		var next [][]string
+		for _, seq := range seqs {
+			for _, p := range primitives {
+				moves := append(append([]string{}, seq...), p)
+				c := labels.Clone()
+				for _, m := range moves {
+					c.Do(m)
+				}
+				if !scanned[c.Face(cube.Up).Pieces[4]] {
+					return moves
+				}
+				next = append(next, moves)
+			}
+		}
+		seqs = next
+	}
+	return nil
+}
+
+// chroma returns the reading with its brightness taken off.
+func chroma(c RGB) [3]float64 {
+	sum := float64(c.R + c.G + c.B)
+	if sum <= 0 {
+		return [3]float64{1.0 / 3, 1.0 / 3, 1.0 / 3}
+	}
+	return [3]float64{float64(c.R) / sum, float64(c.G) / sum, float64(c.B) / sum}
+}
+
+func distance(a, b RGB) float64 {
+	ca, cb := chroma(a), chroma(b)
+	d := 0.0
+	for i := range ca {
+		d += (ca[i] - cb[i]) * (ca[i] - cb[i])
+	}
+	return math.Sqrt(d)
+}
+
+// classify names the colors of the readings, by their labels. The centers
+// get the reference colors which match them best, and the other facelets the
+// color of the closest center, nine facelets a color.
+func classify(readings [54]RGB) map[cube.Color]cube.Color {
+	refs := []cube.Color{cube.White, cube.Yellow, cube.Red, cube.Orange, cube.Green, cube.Blue}
+	var best []cube.Color
+	bestDist := math.Inf(1)
+	permute(refs, 0, func(p []cube.Color) {
+		d := 0.0
+		for i, c := range p {
+			d += distance(readings[i*9+4], referenceColors[c])
+		}
+		if d < bestDist {
+			best, bestDist = append([]cube.Color{}, p...), d
+		}
+	})
+
+	colors := map[cube.Color]cube.Color{}
+	type match struct {
+		label, face int
+		dist        float64
+	}
+	var matches []match
+	for label := range readings {
+		if label%9 == 4 {
+			colors[cube.Color(label)] = best[label/9]
+			continue
+		}
+		for face := 0; face < 6; face++ {
+			matches = append(matches, match{label, face, distance(readings[label], readings[face*9+4])})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].dist < matches[j].dist })
+	count := make([]int, 6)
+	for _, m := range matches {
+		if _, ok := colors[cube.Color(m.label)]; ok || count[m.face] == 8 {
+			continue
+		}
+		colors[cube.Color(m.label)] = best[m.face]
+		count[m.face]++
+	}
+	return colors
+}
+
+// permute calls f with each permutation of s from k on.
+func permute(s []cube.Color, k int, f func([]cube.Color)) {
+	if k == len(s) {
+		f(s)
+		return
+	}
+	for i := k; i < len(s); i++ {
+		s[k], s[i] = s[i], s[k]
+		permute(s, k+1, f)
+		s[k], s[i] = s[i], s[k]
+	}
+}
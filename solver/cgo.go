@@ -0,0 +1,42 @@
+//go:build cgo
+// +build cgo
+
+package solver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ross-wu/cube/kociemba"
+)
+
+// Cgo solves the cubes in process with the C solver of package kociemba.
+type Cgo struct {
+	CacheDir string
+}
+
+// NewCgo returns a cgo solver, and loads the tables from cacheDir.
+func NewCgo(cacheDir string) (Solver, error) {
+	kociemba.Init(cacheDir)
+	return &Cgo{CacheDir: cacheDir}, nil
+}
+
+func (c *Cgo) Solve(ctx context.Context, facelets string, opts Options) ([]string, error) {
+	opts, err := opts.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	s, err := kociemba.SolutionContext(ctx, facelets, opts.MaxDepth, opts.Timeout, false, c.CacheDir)
+	var code kociemba.Error
+	if errors.As(err, &code) {
+		return nil, &Error{Code: Code(code)}
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return strings.Fields(s), nil
+}
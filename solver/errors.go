@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ross-wu/cube/twophase"
+)
+
+// Code classifies why a solve failed. Codes 1 to 8 are the error codes of
+// Kociemba's solver, see kociemba/include/search.h.
+type Code int
+
+const (
+	CodeColorCount Code = 1 + iota // There is not exactly one facelet of each colour
+	CodeEdges                      // Not all 12 edges exist exactly once
+	CodeFlip                       // Flip error: One edge has to be flipped
+	CodeCorners                    // Not all corners exist exactly once
+	CodeTwist                      // Twist error: One corner has to be twisted
+	CodeParity                     // Parity error: Two corners or two edges have to be exchanged
+	CodeNoSolution                 // No solution exists for the given maxDepth
+	CodeTimeout                    // Timeout, no solution within given time
+	CodeCanceled                   // The solve was canceled by the caller
+	CodeFailed                     // The solver failed to run or returned garbage
+)
+
+var codeMessages = map[Code]string{
+	CodeColorCount: "There is not exactly one facelet of each colour",
+	CodeEdges:      "Not all 12 edges exist exactly once",
+	CodeFlip:       "Flip error: One edge has to be flipped",
+	CodeCorners:    "Not all corners exist exactly once",
+	CodeTwist:      "Twist error: One corner has to be twisted",
+	CodeParity:     "Parity error: Two corners or two edges have to be exchanged",
+	CodeNoSolution: "No solution exists for the given maxDepth",
+	CodeTimeout:    "Timeout, no solution within given time",
+	CodeCanceled:   "The solve was canceled",
+	CodeFailed:     "The solver failed",
+}
+
+// Error is the error of a failed solve, all the solvers return it.
+type Error struct {
+	Code Code
+	// Err is the cause of the error if any, e.g. the error of running the
+	// kociemba binary.
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := codeMessages[e.Code]
+	if e.Code <= CodeTimeout {
+		// The codes of Kociemba's solver are formatted like it does.
+		msg = fmt.Sprintf("Error %d: %s", int(e.Code), msg)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// InvalidCube reports whether the error is about the cube itself, i.e. one of
+// the codes 1 to 6, and solving it again can't succeed.
+func (e *Error) InvalidCube() bool {
+	return e.Code >= CodeColorCount && e.Code <= CodeParity
+}
+
+// newError converts an error of a solver to *Error. Context errors become
+// CodeTimeout or CodeCanceled, and errors without a code CodeFailed.
+func newError(err error) *Error {
+	var e *Error
+	var te twophase.Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.As(err, &te):
+		return &Error{Code: Code(te)}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Err: err}
+	}
+	return &Error{Code: CodeFailed, Err: err}
+}
+
+var (
+	errorOutput = regexp.MustCompile(`^Error ([1-8])\b`)
+	moveOutput  = regexp.MustCompile(`^[URFDLB]['2]?$`)
+)
+
+// parseOutput parses the output of the C solver, which is either the moves of
+// the solution or an error code like "Error 3".
+func parseOutput(out string) ([]string, error) {
+	out = strings.TrimSpace(out)
+	if m := errorOutput.FindStringSubmatch(out); m != nil {
+		return nil, &Error{Code: Code(m[1][0] - '0')}
+	}
+	moves := strings.Fields(out)
+	for _, m := range moves {
+		if !moveOutput.MatchString(m) {
+			return nil, &Error{Code: CodeFailed, Err: fmt.Errorf("unexpected solver output %q", out)}
+		}
+	}
+	return moves, nil
+}
@@ -0,0 +1,11 @@
+//go:build !cgo
+// +build !cgo
+
+package solver
+
+import "errors"
+
+// NewCgo returns an error, since the binary is built without cgo.
+func NewCgo(cacheDir string) (Solver, error) {
+	return nil, errors.New("the cgo solver is not available, the binary is built without cgo")
+}
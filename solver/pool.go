@@ -0,0 +1,32 @@
+package solver
+
+import "context"
+
+// Pool bounds the number of cubes a Solver solves concurrently. Solves beyond
+// the bound wait for a free worker, or until their ctx is done.
+type Pool struct {
+	solver  Solver
+	workers chan struct{}
+}
+
+// NewPool returns a pool of n workers sharing s, which must be safe for
+// concurrent use.
+func NewPool(s Solver, n int) *Pool {
+	if n < 1 {
+		n = 1
+	}
+	return &Pool{
+		solver:  s,
+		workers: make(chan struct{}, n),
+	}
+}
+
+func (p *Pool) Solve(ctx context.Context, facelets string, opts Options) ([]string, error) {
+	select {
+	case p.workers <- struct{}{}:
+	case <-ctx.Done():
+		return nil, newError(ctx.Err())
+	}
+	defer func() { <-p.workers }()
+	return p.solver.Solve(ctx, facelets, opts)
+}
@@ -0,0 +1,133 @@
+// Package solver provides the Rubik's cube solvers used by the server: the
+// Kociemba's C solver binary run as a subprocess, the same C solver linked in
+// with cgo, and the native Go solver of package twophase.
+//
+// The cgo and native solvers load their tables once and are safe for
+// concurrent use, wrap them in a Pool to bound the number of concurrent solves.
+package solver
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ross-wu/cube/twophase"
+)
+
+// Defaults and limits of Options, the defaults are the ones of the kociemba
+// binary.
+const (
+	DefaultMaxDepth = 24
+	DefaultTimeout  = 1000 * time.Second
+
+	// MaxDepthLimit is the size of the search arrays of the solvers.
+	MaxDepthLimit = 30
+)
+
+// Options limit the search for one cube. Zero values use the defaults.
+type Options struct {
+	// MaxDepth is the maximal number of moves of the solution.
+	MaxDepth int
+	// Timeout is the maximal search time.
+	Timeout time.Duration
+}
+
+// WithDefaults checks the options and sets the zero values to the defaults.
+func (o Options) WithDefaults() (Options, error) {
+	if o.MaxDepth == 0 {
+		o.MaxDepth = DefaultMaxDepth
+	}
+	if o.MaxDepth < 1 || o.MaxDepth > MaxDepthLimit {
+		return o, fmt.Errorf("max depth must be between 1 and %d, but was %d", MaxDepthLimit, o.MaxDepth)
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.Timeout < 0 {
+		return o, fmt.Errorf("timeout must be positive, but was %v", o.Timeout)
+	}
+	return o, nil
+}
+
+// Solver solves a cube given by Kociemba's facelet string, e.g.
+// "DRLUUBFBRBLURRLRUBLRDDFDLFUFUFFDBRDUBRUFLLFDDBFLUBLRBD", and returns the
+// moves of the solution, e.g. ["D2", "R'", "D'", ...].
+//
+// The search is aborted when ctx is done or opts.Timeout expires. A failed
+// solve returns an *Error, which tells why the cube wasn't solved, invalid
+// options return a plain error.
+type Solver interface {
+	Solve(ctx context.Context, facelets string, opts Options) ([]string, error)
+}
+
+// Exec runs the C solver binary built by kociemba/Makefile for each cube. The
+// binary loads its tables from the "cache" directory each time, and is killed
+// when the search is aborted.
+type Exec struct {
+	// Path of the kociemba binary.
+	Path string
+	// Dir is the working directory of the binary, the current directory if
+	// empty.
+	Dir string
+}
+
+func (e *Exec) Solve(ctx context.Context, facelets string, opts Options) ([]string, error) {
+	opts, err := opts.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.Path,
+		"-d", strconv.Itoa(opts.MaxDepth),
+		"-t", strconv.FormatInt(seconds(opts.Timeout), 10),
+		facelets)
+	cmd.Dir = e.Dir
+	out, err := cmd.Output()
+	switch {
+	case ctx.Err() != nil:
+		return nil, newError(ctx.Err())
+	case err != nil && !errorOutput.Match(out):
+		// The binary exits with status 2 for the error codes, anything else
+		// is a failure.
+		return nil, &Error{Code: CodeFailed, Err: fmt.Errorf("failed to run %q: %v", e.Path, err)}
+	}
+	return parseOutput(string(out))
+}
+
+// seconds rounds d up to seconds, the timeout unit of the C solver.
+func seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// Native solves the cubes in process with package twophase.
+type Native struct {
+	CacheDir string
+}
+
+// NewNative returns a native solver, and loads the tables from cacheDir.
+func NewNative(cacheDir string) (*Native, error) {
+	if err := twophase.InitTables(cacheDir); err != nil {
+		return nil, err
+	}
+	return &Native{CacheDir: cacheDir}, nil
+}
+
+func (n *Native) Solve(ctx context.Context, facelets string, opts Options) ([]string, error) {
+	opts, err := opts.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	s, err := twophase.SolutionContext(ctx, facelets, opts.MaxDepth, false, n.CacheDir)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return strings.Fields(s), nil
+}
@@ -0,0 +1,164 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ross-wu/cube/twophase"
+)
+
+var (
+	kociembaBin = flag.String("kociemba", "../kociemba/bin/kociemba", "Path to the kociemba binary for BenchmarkExec.")
+	cacheDir    = flag.String("cache_dir", "../cache", "Directory of the solver tables.")
+)
+
+// Cubes of in.1, in.2, in.3, in.4, demo.in and input.txt.
+var cubes = []string{
+	"BLLRULRFLDBDDRBRRFDLBFFFUFUBUFRDBBBUDDFLLRLURFDRDBULUU",
+	"RUBBURUUUBFDRRBLFFRRRLFDRUUDFBBDDFLUBUFBLFLDFLLDLBRLDD",
+	"FDFDULLRFDDDBRRRBBUULUFUBFBLLUBDRBRLUBFULFRLDRFRFBLUDD",
+	"LUUBUURFFLRRFRBFDDBUULFRLLRBUUBDRLDRFLDFLFBRUBBDDBDFLD",
+	"UULUUBFDLFRBFRBURBRRDUFDULLRFFBDFBDRBBDLLFLLFDRRDBUDLU",
+	"FBLUUFUBBLRURRDBLFBRDFFDLDRFBDBDUDLUUFRRLLRUDBLRFBULDF",
+}
+
+// oneMove is the cube after U, whose solve time is mostly the overhead of the
+// solver.
+const oneMove = "UUUUUUUUUBBBRRRRRRRRRFFFFFFDDDDDDDDDFFFLLLLLLLLLBBBBBB"
+
+func benchmarkSolver(b *testing.B, s Solver) {
+	// Solve once, so that the tables are cached before timing.
+	if _, err := s.Solve(context.Background(), oneMove, Options{}); err != nil {
+		b.Fatalf("Solve(%q) error: %v", oneMove, err)
+	}
+	b.Run("oneMove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.Solve(context.Background(), oneMove, Options{}); err != nil {
+				b.Fatalf("Solve(%q) error: %v", oneMove, err)
+			}
+		}
+	})
+	b.Run("scrambled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.Solve(context.Background(), cubes[i%len(cubes)], Options{}); err != nil {
+				b.Fatalf("Solve(%q) error: %v", cubes[i%len(cubes)], err)
+			}
+		}
+	})
+}
+
+// BenchmarkExec measures the solver used before, which starts the binary and
+// loads its tables for each cube.
+func BenchmarkExec(b *testing.B) {
+	path, err := filepath.Abs(*kociembaBin)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		b.Skipf("kociemba binary not found, build it with kociemba/Makefile: %v", err)
+	}
+	// The binary keeps its tables in the "cache" directory of its working
+	// directory.
+	dir, err := filepath.Abs(filepath.Dir(*cacheDir))
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkSolver(b, &Exec{Path: path, Dir: dir})
+}
+
+func BenchmarkCgo(b *testing.B) {
+	s, err := NewCgo(*cacheDir)
+	if err != nil {
+		b.Skip(err)
+	}
+	benchmarkSolver(b, s)
+}
+
+func BenchmarkNative(b *testing.B) {
+	s, err := NewNative(*cacheDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkSolver(b, s)
+}
+
+func BenchmarkPoolParallel(b *testing.B) {
+	s, err := NewNative(*cacheDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	p := NewPool(s, 4)
+	if _, err := p.Solve(context.Background(), cubes[0], Options{}); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := p.Solve(context.Background(), cubes[i%len(cubes)], Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestParseOutput checks that the outputs "Error N" of the C solver map to
+// the codes of Error, formatted like the solver does.
+func TestParseOutput(t *testing.T) {
+	for n := 1; n <= 8; n++ {
+		out := fmt.Sprintf("Error %d\n", n)
+		_, err := parseOutput(out)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != Code(n) {
+			t.Errorf("parseOutput(%q) error: %v, want code %d", out, err, n)
+			continue
+		}
+		want := fmt.Sprintf("Error %d: %s", n, codeMessages[Code(n)])
+		if e.Error() != want {
+			t.Errorf("parseOutput(%q) error: %q, want %q", out, e.Error(), want)
+		}
+		if e.InvalidCube() != (n <= 6) {
+			t.Errorf("parseOutput(%q).InvalidCube() = %v", out, e.InvalidCube())
+		}
+	}
+
+	moves, err := parseOutput("D2 R' D' F2 B D R2 D2 R' F2 \n")
+	if want := strings.Fields("D2 R' D' F2 B D R2 D2 R' F2"); err != nil || !reflect.DeepEqual(moves, want) {
+		t.Errorf("parseOutput() = %v, %v, want %v", moves, err, want)
+	}
+	for _, out := range []string{"Error 9", "Segmentation fault", "D2 X"} {
+		_, err := parseOutput(out)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != CodeFailed {
+			t.Errorf("parseOutput(%q) error: %v, want code %d", out, err, CodeFailed)
+		}
+	}
+}
+
+// TestNewError checks the codes of the errors of the solvers.
+func TestNewError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code Code
+	}{
+		{twophase.ErrFlip, CodeFlip},
+		{twophase.ErrNoSolution, CodeNoSolution},
+		{twophase.ErrTimeout, CodeTimeout},
+		{fmt.Errorf("search: %w", twophase.ErrParity), CodeParity},
+		{&Error{Code: CodeCorners}, CodeCorners},
+		{context.DeadlineExceeded, CodeTimeout},
+		{fmt.Errorf("solve: %w", context.Canceled), CodeCanceled},
+		{errors.New("out of memory"), CodeFailed},
+	} {
+		e := newError(tc.err)
+		if e.Code != tc.code {
+			t.Errorf("newError(%v) = code %d, want %d", tc.err, e.Code, tc.code)
+		}
+	}
+}
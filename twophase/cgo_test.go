@@ -0,0 +1,38 @@
+//go:build cgo
+// +build cgo
+
+package twophase_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/kociemba"
+	"github.com/ross-wu/cube/twophase"
+)
+
+// TestSameAsC checks that the solutions and the errors are the ones of the C
+// solver, for the input cubes, random cubes and bad cubes.
+func TestSameAsC(t *testing.T) {
+	cases := []errorCase{}
+	for name, facelets := range inputCubes(t) {
+		cases = append(cases, errorCase{name: name, facelets: facelets, maxDepth: 24})
+		cases = append(cases, errorCase{name: name, facelets: facelets, maxDepth: 5})
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		cases = append(cases, errorCase{name: "random", facelets: cube.RandomCube(r).KociembaScramble(), maxDepth: 22})
+	}
+	cases = append(cases, badCubes...)
+
+	for _, tc := range cases {
+		want, werr := kociemba.Solution(tc.facelets, tc.maxDepth, time.Minute, false, cacheDir)
+		got, err := twophase.Solution(tc.facelets, tc.maxDepth, time.Minute, false, cacheDir)
+		code, _ := werr.(kociemba.Error)
+		if got != want || (werr == nil) != (err == nil) || werr != nil && err != twophase.Error(code) {
+			t.Errorf("%s: Solution(%s, %d) = %q, %v, the C solver %q, %v", tc.name, tc.facelets, tc.maxDepth, got, err, want, werr)
+		}
+	}
+}
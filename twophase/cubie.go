@@ -0,0 +1,725 @@
+package twophase
+
+// Names of the faces, in the order of the facelet string.
+const (
+	U = iota
+	R
+	F
+	D
+	L
+	B
+)
+
+// The names of the corner positions of the cube. Corner URF e.g., has an U(p),
+// a R(ight) and a F(ront) facelet.
+const (
+	URF = iota
+	UFL
+	ULB
+	UBR
+	DFR
+	DLF
+	DBL
+	DRB
+)
+
+// The names of the edge positions of the cube. Edge UR e.g., has an U(p) and
+// R(ight) facelet.
+const (
+	UR = iota
+	UF
+	UL
+	UB
+	DR
+	DF
+	DL
+	DB
+	FR
+	FL
+	BL
+	BR
+)
+
+const (
+	cornerCount = 8
+	edgeCount   = 12
+)
+
+// Facelet positions, U1 is the first facelet of the U face, U2 the second,
+// etc., in the order of the facelet string.
+const (
+	U1 = iota
+	U2
+	U3
+	U4
+	U5
+	U6
+	U7
+	U8
+	U9
+	R1
+	R2
+	R3
+	R4
+	R5
+	R6
+	R7
+	R8
+	R9
+	F1
+	F2
+	F3
+	F4
+	F5
+	F6
+	F7
+	F8
+	F9
+	D1
+	D2
+	D3
+	D4
+	D5
+	D6
+	D7
+	D8
+	D9
+	L1
+	L2
+	L3
+	L4
+	L5
+	L6
+	L7
+	L8
+	L9
+	B1
+	B2
+	B3
+	B4
+	B5
+	B6
+	B7
+	B8
+	B9
+)
+
+var (
+	faceNames = "URFDLB"
+
+	// cornerFacelet maps the corner positions to facelet positions. The first
+	// facelet defines the orientation, the other two follow clockwise.
+	cornerFacelet = [8][3]int{{U9, R1, F3}, {U7, F1, L3}, {U1, L1, B3}, {U3, B1, R3},
+		{D3, F9, R7}, {D1, L9, F7}, {D7, B9, L7}, {D9, R9, B7}}
+
+	// edgeFacelet maps the edge positions to facelet positions. The first
+	// facelet defines the orientation.
+	edgeFacelet = [12][2]int{{U6, R2}, {U8, F2}, {U4, L2}, {U2, B2}, {D6, R8}, {D2, F8},
+		{D4, L8}, {D8, B8}, {F6, R4}, {F4, L6}, {B6, L4}, {B4, R6}}
+
+	// cornerColor maps the corner positions to facelet colors.
+	cornerColor = [8][3]int{{U, R, F}, {U, F, L}, {U, L, B}, {U, B, R}, {D, F, R}, {D, L, F},
+		{D, B, L}, {D, R, B}}
+
+	// edgeColor maps the edge positions to facelet colors.
+	edgeColor = [12][2]int{{U, R}, {U, F}, {U, L}, {U, B}, {D, R}, {D, F}, {D, L}, {D, B},
+		{F, R}, {F, L}, {B, L}, {B, R}}
+)
+
+// cubieCube is the cube on the cubie level: the corner and edge at each
+// position and their orientations.
+type cubieCube struct {
+	cp [8]int
+	co [8]int
+	ep [12]int
+	eo [12]int
+}
+
+// moveCube holds the 6 basic face turns U, R, F, D, L and B.
+var moveCube = [6]cubieCube{
+	{
+		cp: [8]int{UBR, URF, UFL, ULB, DFR, DLF, DBL, DRB},
+		ep: [12]int{UB, UR, UF, UL, DR, DF, DL, DB, FR, FL, BL, BR},
+	},
+	{
+		cp: [8]int{DFR, UFL, ULB, URF, DRB, DLF, DBL, UBR},
+		co: [8]int{2, 0, 0, 1, 1, 0, 0, 2},
+		ep: [12]int{FR, UF, UL, UB, BR, DF, DL, DB, DR, FL, BL, UR},
+	},
+	{
+		cp: [8]int{UFL, DLF, ULB, UBR, URF, DFR, DBL, DRB},
+		co: [8]int{1, 2, 0, 0, 2, 1, 0, 0},
+		ep: [12]int{UR, FL, UL, UB, DR, FR, DL, DB, UF, DF, BL, BR},
+		eo: [12]int{0, 1, 0, 0, 0, 1, 0, 0, 1, 1, 0, 0},
+	},
+	{
+		cp: [8]int{URF, UFL, ULB, UBR, DLF, DBL, DRB, DFR},
+		ep: [12]int{UR, UF, UL, UB, DF, DL, DB, DR, FR, FL, BL, BR},
+	},
+	{
+		cp: [8]int{URF, ULB, DBL, UBR, DFR, UFL, DLF, DRB},
+		co: [8]int{0, 1, 2, 0, 0, 2, 1, 0},
+		ep: [12]int{UR, UF, BL, UB, DR, DF, FL, DB, FR, UL, DL, BR},
+	},
+	{
+		cp: [8]int{URF, UFL, UBR, DRB, DFR, DLF, ULB, DBL},
+		co: [8]int{0, 0, 1, 2, 0, 0, 2, 1},
+		ep: [12]int{UR, UF, UL, BR, DR, DF, DL, BL, FR, FL, UB, DB},
+		eo: [12]int{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 1, 1},
+	},
+}
+
+func newCubieCube() *cubieCube {
+	return &cubieCube{
+		cp: [8]int{URF, UFL, ULB, UBR, DFR, DLF, DBL, DRB},
+		ep: [12]int{UR, UF, UL, UB, DR, DF, DL, DB, FR, FL, BL, BR},
+	}
+}
+
+// toCubieCube converts a facelet string to the cubie level. The facelet
+// string must contain 54 valid face names.
+func toCubieCube(facelets string) *cubieCube {
+	f := make([]int, len(facelets))
+	for i := range facelets {
+		for code := range faceNames {
+			if faceNames[code] == facelets[i] {
+				f[i] = code
+			}
+		}
+	}
+
+	cc := &cubieCube{}
+	for i := 0; i < cornerCount; i++ {
+		// get the colors of the cubie at corner i, starting with U/D
+		var ori int
+		for ori = 0; ori < 3; ori++ {
+			if f[cornerFacelet[i][ori]] == U || f[cornerFacelet[i][ori]] == D {
+				break
+			}
+		}
+		col1 := f[cornerFacelet[i][(ori+1)%3]]
+		col2 := f[cornerFacelet[i][(ori+2)%3]]
+
+		for j := 0; j < cornerCount; j++ {
+			if col1 == cornerColor[j][1] && col2 == cornerColor[j][2] {
+				// in cornerposition i we have cornercubie j
+				cc.cp[i] = j
+				cc.co[i] = ori % 3
+				break
+			}
+		}
+	}
+
+	for i := 0; i < edgeCount; i++ {
+		for j := 0; j < edgeCount; j++ {
+			if f[edgeFacelet[i][0]] == edgeColor[j][0] && f[edgeFacelet[i][1]] == edgeColor[j][1] {
+				cc.ep[i] = j
+				cc.eo[i] = 0
+				break
+			}
+			if f[edgeFacelet[i][0]] == edgeColor[j][1] && f[edgeFacelet[i][1]] == edgeColor[j][0] {
+				cc.ep[i] = j
+				cc.eo[i] = 1
+				break
+			}
+		}
+	}
+	return cc
+}
+
+// facelets converts the cube back to a facelet string.
+func (cc *cubieCube) facelets() string {
+	f := make([]byte, 54)
+	for i := range f {
+		f[i] = faceNames[i/9]
+	}
+	for i := 0; i < cornerCount; i++ {
+		j, ori := cc.cp[i], cc.co[i]
+		for n := 0; n < 3; n++ {
+			f[cornerFacelet[i][(n+ori)%3]] = faceNames[cornerColor[j][n]]
+		}
+	}
+	for i := 0; i < edgeCount; i++ {
+		j, ori := cc.ep[i], cc.eo[i]
+		for n := 0; n < 2; n++ {
+			f[edgeFacelet[i][(n+ori)%2]] = faceNames[edgeColor[j][n]]
+		}
+	}
+	return string(f)
+}
+
+// cornerMultiply multiplies the corners of cc with b: cc = cc*b.
+func (cc *cubieCube) cornerMultiply(b *cubieCube) {
+	var cPerm, cOri [8]int
+	for corn := 0; corn < cornerCount; corn++ {
+		cPerm[corn] = cc.cp[b.cp[corn]]
+		cOri[corn] = (cc.co[b.cp[corn]] + b.co[corn]) % 3
+	}
+	cc.cp, cc.co = cPerm, cOri
+}
+
+// edgeMultiply multiplies the edges of cc with b: cc = cc*b.
+func (cc *cubieCube) edgeMultiply(b *cubieCube) {
+	var ePerm, eOri [12]int
+	for edge := 0; edge < edgeCount; edge++ {
+		ePerm[edge] = cc.ep[b.ep[edge]]
+		eOri[edge] = (b.eo[edge] + cc.eo[b.ep[edge]]) % 2
+	}
+	cc.ep, cc.eo = ePerm, eOri
+}
+
+func (cc *cubieCube) multiply(b *cubieCube) {
+	cc.cornerMultiply(b)
+	cc.edgeMultiply(b)
+}
+
+// inverse returns the inverse cube of cc.
+func (cc *cubieCube) inverse() *cubieCube {
+	c := &cubieCube{}
+	for edge := 0; edge < edgeCount; edge++ {
+		c.ep[cc.ep[edge]] = edge
+	}
+	for edge := 0; edge < edgeCount; edge++ {
+		c.eo[edge] = cc.eo[c.ep[edge]]
+	}
+	for corn := 0; corn < cornerCount; corn++ {
+		c.cp[cc.cp[corn]] = corn
+	}
+	for corn := 0; corn < cornerCount; corn++ {
+		c.co[corn] = (3 - cc.co[c.cp[corn]]) % 3
+	}
+	return c
+}
+
+// cnk returns n choose k.
+func cnk(n, k int) int {
+	if n < k {
+		return 0
+	}
+	if k > n/2 {
+		k = n - k
+	}
+	s := 1
+	for i, j := n, 1; i != n-k; i, j = i-1, j+1 {
+		s *= i
+		s /= j
+	}
+	return s
+}
+
+// rotateLeft rotates left all array elements between l and r.
+func rotateLeft(arr []int, l, r int) {
+	temp := arr[l]
+	for i := l; i < r; i++ {
+		arr[i] = arr[i+1]
+	}
+	arr[r] = temp
+}
+
+// rotateRight rotates right all array elements between l and r.
+func rotateRight(arr []int, l, r int) {
+	temp := arr[r]
+	for i := r; i > l; i-- {
+		arr[i] = arr[i-1]
+	}
+	arr[l] = temp
+}
+
+// twist is the orientation of the 8 corners, 0 <= twist < 3^7.
+func (cc *cubieCube) twist() int {
+	ret := 0
+	for i := URF; i < DRB; i++ {
+		ret = 3*ret + cc.co[i]
+	}
+	return ret
+}
+
+func (cc *cubieCube) setTwist(twist int) {
+	twistParity := 0
+	for i := DRB - 1; i >= URF; i-- {
+		cc.co[i] = twist % 3
+		twistParity += cc.co[i]
+		twist /= 3
+	}
+	cc.co[DRB] = (3 - twistParity%3) % 3
+}
+
+// flip is the orientation of the 12 edges, 0 <= flip < 2^11.
+func (cc *cubieCube) flip() int {
+	ret := 0
+	for i := UR; i < BR; i++ {
+		ret = 2*ret + cc.eo[i]
+	}
+	return ret
+}
+
+func (cc *cubieCube) setFlip(flip int) {
+	flipParity := 0
+	for i := BR - 1; i >= UR; i-- {
+		cc.eo[i] = flip % 2
+		flipParity += cc.eo[i]
+		flip /= 2
+	}
+	cc.eo[BR] = (2 - flipParity%2) % 2
+}
+
+// permParity returns the parity of a permutation.
+func permParity(perm []int) int {
+	s := 0
+	for i := len(perm) - 1; i >= 1; i-- {
+		for j := i - 1; j >= 0; j-- {
+			if perm[j] > perm[i] {
+				s++
+			}
+		}
+	}
+	return s % 2
+}
+
+func (cc *cubieCube) cornerParity() int {
+	return permParity(cc.cp[:])
+}
+
+func (cc *cubieCube) edgeParity() int {
+	return permParity(cc.ep[:])
+}
+
+// FRtoBR is the permutation of the UD-slice edges FR, FL, BL and BR,
+// 0 <= FRtoBR < 11880.
+func (cc *cubieCube) FRtoBR() int {
+	a, x, b := 0, 0, 0
+	var edge4 [4]int
+	// compute the index a < (12 choose 4) and the permutation array perm.
+	for j := BR; j >= UR; j-- {
+		if FR <= cc.ep[j] && cc.ep[j] <= BR {
+			a += cnk(11-j, x+1)
+			edge4[3-x] = cc.ep[j]
+			x++
+		}
+	}
+	// compute the index b < 4! for the permutation in perm
+	for j := 3; j > 0; j-- {
+		k := 0
+		for edge4[j] != j+8 {
+			rotateLeft(edge4[:], 0, j)
+			k++
+		}
+		b = (j+1)*b + k
+	}
+	return 24*a + b
+}
+
+func (cc *cubieCube) setFRtoBR(idx int) {
+	sliceEdge := []int{FR, FL, BL, BR}
+	otherEdge := []int{UR, UF, UL, UB, DR, DF, DL, DB}
+	b := idx % 24 // Permutation
+	a := idx / 24 // Combination
+	for e := 0; e < edgeCount; e++ {
+		cc.ep[e] = DB // Use DB to invalidate all edges
+	}
+	// generate permutation from index b
+	for j := 1; j < 4; j++ {
+		k := b % (j + 1)
+		b /= j + 1
+		for ; k > 0; k-- {
+			rotateRight(sliceEdge, 0, j)
+		}
+	}
+	// generate combination and set slice edges
+	x := 3
+	for j := UR; j <= BR; j++ {
+		if a-cnk(11-j, x+1) >= 0 {
+			cc.ep[j] = sliceEdge[3-x]
+			a -= cnk(11-j, x+1)
+			x--
+		}
+	}
+	// set the remaining edges UR..DB
+	x = 0
+	for j := UR; j <= BR; j++ {
+		if cc.ep[j] == DB {
+			cc.ep[j] = otherEdge[x]
+			x++
+		}
+	}
+}
+
+// URFtoDLF is the permutation of the corners URF, UFL, ULB, UBR, DFR and DLF,
+// 0 <= URFtoDLF < 20160.
+func (cc *cubieCube) URFtoDLF() int {
+	a, x, b := 0, 0, 0
+	var corner6 [6]int
+	// compute the index a < (8 choose 6) and the corner permutation.
+	for j := URF; j <= DRB; j++ {
+		if cc.cp[j] <= DLF {
+			a += cnk(j, x+1)
+			corner6[x] = cc.cp[j]
+			x++
+		}
+	}
+	// compute the index b < 6! for the permutation in corner6
+	for j := 5; j > 0; j-- {
+		k := 0
+		for corner6[j] != j {
+			rotateLeft(corner6[:], 0, j)
+			k++
+		}
+		b = (j+1)*b + k
+	}
+	return 720*a + b
+}
+
+func (cc *cubieCube) setURFtoDLF(idx int) {
+	corner6 := []int{URF, UFL, ULB, UBR, DFR, DLF}
+	otherCorner := []int{DBL, DRB}
+	b := idx % 720 // Permutation
+	a := idx / 720 // Combination
+	for c := 0; c < cornerCount; c++ {
+		cc.cp[c] = DRB // Use DRB to invalidate all corners
+	}
+	// generate permutation from index b
+	for j := 1; j < 6; j++ {
+		k := b % (j + 1)
+		b /= j + 1
+		for ; k > 0; k-- {
+			rotateRight(corner6, 0, j)
+		}
+	}
+	// generate combination and set corners
+	x := 5
+	for j := DRB; j >= 0; j-- {
+		if a-cnk(j, x+1) >= 0 {
+			cc.cp[j] = corner6[x]
+			a -= cnk(j, x+1)
+			x--
+		}
+	}
+	x = 0
+	for j := URF; j <= DRB; j++ {
+		if cc.cp[j] == DRB {
+			cc.cp[j] = otherCorner[x]
+			x++
+		}
+	}
+}
+
+// URtoDF is the permutation of the edges UR, UF, UL, UB, DR and DF,
+// 0 <= URtoDF < 665280.
+func (cc *cubieCube) URtoDF() int {
+	a, x, b := 0, 0, 0
+	var edge6 [6]int
+	// compute the index a < (12 choose 6) and the edge permutation.
+	for j := UR; j <= BR; j++ {
+		if cc.ep[j] <= DF {
+			a += cnk(j, x+1)
+			edge6[x] = cc.ep[j]
+			x++
+		}
+	}
+	// compute the index b < 6! for the permutation in edge6
+	for j := 5; j > 0; j-- {
+		k := 0
+		for edge6[j] != j {
+			rotateLeft(edge6[:], 0, j)
+			k++
+		}
+		b = (j+1)*b + k
+	}
+	return 720*a + b
+}
+
+func (cc *cubieCube) setURtoDF(idx int) {
+	edge6 := []int{UR, UF, UL, UB, DR, DF}
+	otherEdge := []int{DL, DB, FR, FL, BL, BR}
+	b := idx % 720 // Permutation
+	a := idx / 720 // Combination
+	for e := 0; e < edgeCount; e++ {
+		cc.ep[e] = BR // Use BR to invalidate all edges
+	}
+	// generate permutation from index b
+	for j := 1; j < 6; j++ {
+		k := b % (j + 1)
+		b /= j + 1
+		for ; k > 0; k-- {
+			rotateRight(edge6, 0, j)
+		}
+	}
+	// generate combination and set edges
+	x := 5
+	for j := BR; j >= 0; j-- {
+		if a-cnk(j, x+1) >= 0 {
+			cc.ep[j] = edge6[x]
+			a -= cnk(j, x+1)
+			x--
+		}
+	}
+	// set the remaining edges DL..BR
+	x = 0
+	for j := UR; j <= BR; j++ {
+		if cc.ep[j] == BR {
+			cc.ep[j] = otherEdge[x]
+			x++
+		}
+	}
+}
+
+// URtoUL is the permutation of the edges UR, UF and UL, 0 <= URtoUL < 1320.
+func (cc *cubieCube) URtoUL() int {
+	a, x, b := 0, 0, 0
+	var edge3 [3]int
+	// compute the index a < (12 choose 3) and the edge permutation.
+	for j := UR; j <= BR; j++ {
+		if cc.ep[j] <= UL {
+			a += cnk(j, x+1)
+			edge3[x] = cc.ep[j]
+			x++
+		}
+	}
+	// compute the index b < 3! for the permutation in edge3
+	for j := 2; j > 0; j-- {
+		k := 0
+		for edge3[j] != j {
+			rotateLeft(edge3[:], 0, j)
+			k++
+		}
+		b = (j+1)*b + k
+	}
+	return 6*a + b
+}
+
+func (cc *cubieCube) setURtoUL(idx int) {
+	edge3 := []int{UR, UF, UL}
+	b := idx % 6 // Permutation
+	a := idx / 6 // Combination
+	for e := 0; e < edgeCount; e++ {
+		cc.ep[e] = BR // Use BR to invalidate all edges
+	}
+	// generate permutation from index b
+	for j := 1; j < 3; j++ {
+		k := b % (j + 1)
+		b /= j + 1
+		for ; k > 0; k-- {
+			rotateRight(edge3, 0, j)
+		}
+	}
+	// generate combination and set edges
+	x := 2
+	for j := BR; j >= 0; j-- {
+		if a-cnk(j, x+1) >= 0 {
+			cc.ep[j] = edge3[x]
+			a -= cnk(j, x+1)
+			x--
+		}
+	}
+}
+
+// UBtoDF is the permutation of the edges UB, DR and DF, 0 <= UBtoDF < 1320.
+func (cc *cubieCube) UBtoDF() int {
+	a, x, b := 0, 0, 0
+	var edge3 [3]int
+	// compute the index a < (12 choose 3) and the edge permutation.
+	for j := UR; j <= BR; j++ {
+		if UB <= cc.ep[j] && cc.ep[j] <= DF {
+			a += cnk(j, x+1)
+			edge3[x] = cc.ep[j]
+			x++
+		}
+	}
+	// compute the index b < 3! for the permutation in edge3
+	for j := 2; j > 0; j-- {
+		k := 0
+		for edge3[j] != UB+j {
+			rotateLeft(edge3[:], 0, j)
+			k++
+		}
+		b = (j+1)*b + k
+	}
+	return 6*a + b
+}
+
+func (cc *cubieCube) setUBtoDF(idx int) {
+	edge3 := []int{UB, DR, DF}
+	b := idx % 6 // Permutation
+	a := idx / 6 // Combination
+	for e := 0; e < edgeCount; e++ {
+		cc.ep[e] = BR // Use BR to invalidate all edges
+	}
+	// generate permutation from index b
+	for j := 1; j < 3; j++ {
+		k := b % (j + 1)
+		b /= j + 1
+		for ; k > 0; k-- {
+			rotateRight(edge3, 0, j)
+		}
+	}
+	// generate combination and set edges
+	x := 2
+	for j := BR; j >= 0; j-- {
+		if a-cnk(j, x+1) >= 0 {
+			cc.ep[j] = edge3[x]
+			a -= cnk(j, x+1)
+			x--
+		}
+	}
+}
+
+// verify checks the cube and returns 0 if it's solvable, or the error code
+// of Solution otherwise.
+func (cc *cubieCube) verify() Error {
+	var edgeCount, cornerCount [12]int
+	for _, e := range cc.ep {
+		edgeCount[e]++
+	}
+	for i := 0; i < 12; i++ {
+		if edgeCount[i] != 1 {
+			return ErrEdges
+		}
+	}
+
+	sum := 0
+	for _, eo := range cc.eo {
+		sum += eo
+	}
+	if sum%2 != 0 {
+		return ErrFlip
+	}
+
+	for _, c := range cc.cp {
+		cornerCount[c]++
+	}
+	for i := 0; i < 8; i++ {
+		if cornerCount[i] != 1 {
+			return ErrCorners
+		}
+	}
+
+	sum = 0
+	for _, co := range cc.co {
+		sum += co
+	}
+	if sum%3 != 0 {
+		return ErrTwist
+	}
+
+	if cc.edgeParity()^cc.cornerParity() != 0 {
+		return ErrParity
+	}
+	return 0
+}
+
+// mergeURtoDF merges the coordinates URtoUL and UBtoDF to URtoDF. It returns
+// -1 if the edges collide.
+func mergeURtoDF(idx1, idx2 int) int {
+	a, b := newCubieCube(), newCubieCube()
+	a.setURtoUL(idx1)
+	b.setUBtoDF(idx2)
+	for i := 0; i < 8; i++ {
+		if a.ep[i] != BR {
+			if b.ep[i] != BR { // collision
+				return -1
+			}
+			b.ep[i] = a.ep[i]
+		}
+	}
+	return b.URtoDF()
+}
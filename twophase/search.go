@@ -0,0 +1,357 @@
+// Package twophase is a pure Go implementation of Herbert Kociemba's two-phase
+// algorithm for solving Rubik's Cube. It's a port of the C solver in kociemba/
+// and returns the same solutions and error codes.
+//
+// Usage:
+//
+//	s, err := twophase.Solution("DRLUUBFBRBLURRLRUBLRDDFDLFUFUFFDBRDUBRUFLLFDDBFLUBLRBD",
+//	    24, time.Second, false, "cache")
+//
+// See kociemba/include/facelet.h for the format of the facelet string.
+package twophase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Error is an error code of Solution.
+type Error int
+
+const (
+	ErrColorCount Error = 1 + iota // There is not exactly one facelet of each colour
+	ErrEdges                       // Not all 12 edges exist exactly once
+	ErrFlip                        // Flip error: One edge has to be flipped
+	ErrCorners                     // Not all corners exist exactly once
+	ErrTwist                       // Twist error: One corner has to be twisted
+	ErrParity                      // Parity error: Two corners or two edges have to be exchanged
+	ErrNoSolution                  // No solution exists for the given maxDepth
+	ErrTimeout                     // Timeout, no solution within given time
+)
+
+var errorMessages = map[Error]string{
+	ErrColorCount: "There is not exactly one facelet of each colour",
+	ErrEdges:      "Not all 12 edges exist exactly once",
+	ErrFlip:       "Flip error: One edge has to be flipped",
+	ErrCorners:    "Not all corners exist exactly once",
+	ErrTwist:      "Twist error: One corner has to be twisted",
+	ErrParity:     "Parity error: Two corners or two edges have to be exchanged",
+	ErrNoSolution: "No solution exists for the given maxDepth",
+	ErrTimeout:    "Timeout, no solution within given time",
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("Error %d: %s", int(e), errorMessages[e])
+}
+
+// search holds the state of the IDA* search.
+type search struct {
+	ax            [31]int // The axis of the move
+	po            [31]int // The power of the move
+	flip          [31]int // phase1 coordinates
+	twist         [31]int
+	slice         [31]int
+	parity        [31]int // phase2 coordinates
+	URFtoDLF      [31]int
+	FRtoBR        [31]int
+	URtoUL        [31]int
+	UBtoDF        [31]int
+	URtoDF        [31]int
+	minDistPhase1 [31]int // IDA* distance do goal estimations
+	minDistPhase2 [31]int
+}
+
+// solutionToString generates the solution string, with a separator between
+// the phase1 and phase2 moves if depthPhase1 >= 0.
+func (s *search) solutionToString(length, depthPhase1 int) string {
+	var b strings.Builder
+	for i := 0; i < length; i++ {
+		b.WriteByte(faceNames[s.ax[i]])
+		switch s.po[i] {
+		case 1:
+			b.WriteByte(' ')
+		case 2:
+			b.WriteString("2 ")
+		case 3:
+			b.WriteString("' ")
+		}
+		if i == depthPhase1-1 {
+			b.WriteString(". ")
+		}
+	}
+	return b.String()
+}
+
+// Solution computes the solver string for a given cube.
+//
+// facelets is the cube definition string, see kociemba/include/facelet.h for
+// the format.
+//
+// maxDepth defines the maximal allowed maneuver length. For random cubes, a
+// maxDepth of 21 usually will return a solution in less than 0.5 seconds. With
+// a maxDepth of 20 it takes a few seconds on average to find a solution, but it
+// may take much longer for specific cubes.
+//
+// timeout defines the maximum computing time of the method. If it does not
+// return with a solution, it returns ErrTimeout.
+//
+// useSeparator determines if a " . " separates the phase1 and phase2 parts of
+// the solver string like in F' R B R L2 F . U2 U D for example.
+//
+// cacheDir is passed to InitTables.
+//
+// The solution string has the same format as the one of the C solver: the
+// moves separated and followed by a space. The error is one of the Error codes,
+// or an error of InitTables.
+func Solution(facelets string, maxDepth int, timeout time.Duration, useSeparator bool, cacheDir string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	s, err := SolutionContext(ctx, facelets, maxDepth, useSeparator, cacheDir)
+	if err == context.DeadlineExceeded {
+		return "", ErrTimeout
+	}
+	return s, err
+}
+
+// SolutionContext is Solution with the search aborted when ctx is done instead
+// of after a timeout, it returns ctx.Err() then.
+func SolutionContext(ctx context.Context, facelets string, maxDepth int, useSeparator bool, cacheDir string) (string, error) {
+	if err := InitTables(cacheDir); err != nil {
+		return "", err
+	}
+
+	// +++++++++++++++++++++check for wrong input +++++++++++++++++++++++++++++
+	var count [6]int
+	for i := 0; i < len(facelets) && i < 54; i++ {
+		if code := strings.IndexByte(faceNames, facelets[i]); code >= 0 {
+			count[code]++
+		}
+	}
+	for i := 0; i < 6; i++ {
+		if count[i] != 9 {
+			return "", ErrColorCount
+		}
+	}
+
+	cc := toCubieCube(facelets)
+	if err := cc.verify(); err != 0 {
+		return "", err
+	}
+
+	// +++++++++++++++++++++++ initialization +++++++++++++++++++++++++++++++++
+	s := &search{}
+	s.flip[0] = cc.flip()
+	s.twist[0] = cc.twist()
+	s.parity[0] = cc.cornerParity()
+	s.slice[0] = cc.FRtoBR() / 24
+	s.URFtoDLF[0] = cc.URFtoDLF()
+	s.FRtoBR[0] = cc.FRtoBR()
+	s.URtoUL[0] = cc.URtoUL()
+	s.UBtoDF[0] = cc.UBtoDF()
+
+	s.minDistPhase1[1] = 1 // else failure for depth=1, n=0
+	n := 0
+	busy := false
+	depthPhase1 := 1
+
+	done := ctx.Done()
+
+	// +++++++++++++++++++ Main loop ++++++++++++++++++++++++++++++++++++++++++
+	for {
+		for {
+			if depthPhase1-n > s.minDistPhase1[n+1] && !busy {
+				// Initialize next move
+				if s.ax[n] == 0 || s.ax[n] == 3 {
+					n++
+					s.ax[n] = 1
+				} else {
+					n++
+					s.ax[n] = 0
+				}
+				s.po[n] = 1
+			} else if s.po[n]++; s.po[n] > 3 {
+				for { // increment axis
+					if s.ax[n]++; s.ax[n] > 5 {
+						select {
+						case <-done:
+							return "", ctx.Err()
+						default:
+						}
+						if n == 0 {
+							if depthPhase1 >= maxDepth {
+								return "", ErrNoSolution
+							}
+							depthPhase1++
+							s.ax[n] = 0
+							s.po[n] = 1
+							busy = false
+							break
+						}
+						n--
+						busy = true
+						break
+					}
+					s.po[n] = 1
+					busy = false
+					if !(n != 0 && (s.ax[n-1] == s.ax[n] || s.ax[n-1]-3 == s.ax[n])) {
+						break
+					}
+				}
+			} else {
+				busy = false
+			}
+			if !busy {
+				break
+			}
+		}
+
+		// +++++++++++++ compute new coordinates and new minDistPhase1 ++++++++++
+		// if minDistPhase1 =0, the H subgroup is reached
+		mv := 3*s.ax[n] + s.po[n] - 1
+		s.flip[n+1] = int(flipMove[s.flip[n]][mv])
+		s.twist[n+1] = int(twistMove[s.twist[n]][mv])
+		s.slice[n+1] = int(FRtoBRMove[s.slice[n]*24][mv]) / 24
+		s.minDistPhase1[n+1] = max(
+			int(getPruning(sliceFlipPrun[:], nSlice1*s.flip[n+1]+s.slice[n+1])),
+			int(getPruning(sliceTwistPrun[:], nSlice1*s.twist[n+1]+s.slice[n+1])),
+		)
+		// ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+
+		if s.minDistPhase1[n+1] == 0 && n >= depthPhase1-5 {
+			s.minDistPhase1[n+1] = 10 // instead of 10 any value >5 is possible
+			if n == depthPhase1-1 {
+				if length := s.totalDepth(depthPhase1, maxDepth); length >= 0 {
+					if length == depthPhase1 ||
+						(s.ax[depthPhase1-1] != s.ax[depthPhase1] && s.ax[depthPhase1-1] != s.ax[depthPhase1]+3) {
+						if useSeparator {
+							return s.solutionToString(length, depthPhase1), nil
+						}
+						return s.solutionToString(length, -1), nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// totalDepth applies phase2 of algorithm and returns the combined phase1 and
+// phase2 depth, or -1 if there's no phase2 solution within maxDepth. In phase2,
+// only the moves U,D,R2,F2,L2 and B2 are allowed.
+func (s *search) totalDepth(depthPhase1, maxDepth int) int {
+	maxDepthPhase2 := min(10, maxDepth-depthPhase1) // Allow only max 10 moves in phase2
+	for i := 0; i < depthPhase1; i++ {
+		mv := 3*s.ax[i] + s.po[i] - 1
+		s.URFtoDLF[i+1] = int(URFtoDLFMove[s.URFtoDLF[i]][mv])
+		s.FRtoBR[i+1] = int(FRtoBRMove[s.FRtoBR[i]][mv])
+		s.parity[i+1] = int(parityMove[s.parity[i]][mv])
+	}
+
+	d1 := int(getPruning(sliceURFtoDLFParityPrun[:],
+		(nSlice2*s.URFtoDLF[depthPhase1]+s.FRtoBR[depthPhase1])*2+s.parity[depthPhase1]))
+	if d1 > maxDepthPhase2 {
+		return -1
+	}
+
+	for i := 0; i < depthPhase1; i++ {
+		mv := 3*s.ax[i] + s.po[i] - 1
+		s.URtoUL[i+1] = int(URtoULMove[s.URtoUL[i]][mv])
+		s.UBtoDF[i+1] = int(UBtoDFMove[s.UBtoDF[i]][mv])
+	}
+	s.URtoDF[depthPhase1] = int(mergeURtoULandUBtoDF[s.URtoUL[depthPhase1]][s.UBtoDF[depthPhase1]])
+
+	d2 := int(getPruning(sliceURtoDFParityPrun[:],
+		(nSlice2*s.URtoDF[depthPhase1]+s.FRtoBR[depthPhase1])*2+s.parity[depthPhase1]))
+	if d2 > maxDepthPhase2 {
+		return -1
+	}
+
+	if s.minDistPhase2[depthPhase1] = max(d1, d2); s.minDistPhase2[depthPhase1] == 0 {
+		return depthPhase1 // already solved
+	}
+
+	// now set up search
+	depthPhase2 := 1
+	n := depthPhase1
+	busy := false
+	s.po[depthPhase1] = 0
+	s.ax[depthPhase1] = 0
+	s.minDistPhase2[n+1] = 1 // else failure for depthPhase2=1, n=0
+	// +++++++++++++++++++ end initialization +++++++++++++++++++++++++++++++++
+	for {
+		for {
+			if depthPhase1+depthPhase2-n > s.minDistPhase2[n+1] && !busy {
+				// Initialize next move
+				if s.ax[n] == 0 || s.ax[n] == 3 {
+					n++
+					s.ax[n] = 1
+					s.po[n] = 2
+				} else {
+					n++
+					s.ax[n] = 0
+					s.po[n] = 1
+				}
+			} else if s.nextPhase2Power(n) > 3 {
+				for { // increment axis
+					if s.ax[n]++; s.ax[n] > 5 {
+						if n == depthPhase1 {
+							if depthPhase2 >= maxDepthPhase2 {
+								return -1
+							}
+							depthPhase2++
+							s.ax[n] = 0
+							s.po[n] = 1
+							busy = false
+							break
+						}
+						n--
+						busy = true
+						break
+					}
+					if s.ax[n] == 0 || s.ax[n] == 3 {
+						s.po[n] = 1
+					} else {
+						s.po[n] = 2
+					}
+					busy = false
+					if !(n != depthPhase1 && (s.ax[n-1] == s.ax[n] || s.ax[n-1]-3 == s.ax[n])) {
+						break
+					}
+				}
+			} else {
+				busy = false
+			}
+			if !busy {
+				break
+			}
+		}
+		// +++++++++++++ compute new coordinates and new minDist ++++++++++
+		mv := 3*s.ax[n] + s.po[n] - 1
+
+		s.URFtoDLF[n+1] = int(URFtoDLFMove[s.URFtoDLF[n]][mv])
+		s.FRtoBR[n+1] = int(FRtoBRMove[s.FRtoBR[n]][mv])
+		s.parity[n+1] = int(parityMove[s.parity[n]][mv])
+		s.URtoDF[n+1] = int(URtoDFMove[s.URtoDF[n]][mv])
+
+		s.minDistPhase2[n+1] = max(
+			int(getPruning(sliceURtoDFParityPrun[:], (nSlice2*s.URtoDF[n+1]+s.FRtoBR[n+1])*2+s.parity[n+1])),
+			int(getPruning(sliceURFtoDLFParityPrun[:], (nSlice2*s.URFtoDLF[n+1]+s.FRtoBR[n+1])*2+s.parity[n+1])),
+		)
+		// ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+		if s.minDistPhase2[n+1] == 0 {
+			return depthPhase1 + depthPhase2
+		}
+	}
+}
+
+// nextPhase2Power increments the power of the move at n to the next one
+// allowed in phase2 and returns it: U and D turn by 1, the others by 2.
+func (s *search) nextPhase2Power(n int) int {
+	if s.ax[n] == 0 || s.ax[n] == 3 {
+		s.po[n]++
+	} else {
+		s.po[n] += 2
+	}
+	return s.po[n]
+}
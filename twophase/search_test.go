@@ -0,0 +1,134 @@
+package twophase_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ross-wu/cube/cube"
+	"github.com/ross-wu/cube/solver"
+	"github.com/ross-wu/cube/twophase"
+)
+
+const cacheDir = "../cache"
+
+// solved is the facelet string of the solved cube.
+const solved = "UUUUUUUUURRRRRRRRRFFFFFFFFFDDDDDDDDDLLLLLLLLLBBBBBBBBB"
+
+// inputCubes returns the cubes of in.1, in.2, in.3, in.4, demo.in and
+// input.txt by file name, as facelet strings.
+func inputCubes(t *testing.T) map[string]string {
+	t.Helper()
+	cubes := map[string]string{}
+	for _, name := range []string{"in.1", "in.2", "in.3", "in.4", "demo.in", "input.txt"} {
+		data, err := os.ReadFile("../" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := cube.ParseCube(cube.FormatRows, string(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cubes[name] = c.KociembaScramble()
+	}
+	return cubes
+}
+
+// set returns the facelets with the facelets at the indexes set to the faces.
+func set(facelets string, faces map[int]byte) string {
+	b := []byte(facelets)
+	for i, f := range faces {
+		b[i] = f
+	}
+	return string(b)
+}
+
+// errorCase is a cube which the C solver fails to solve within maxDepth
+// moves, with its error.
+type errorCase struct {
+	name     string
+	facelets string
+	maxDepth int
+	err      twophase.Error
+}
+
+// badCubes are cubes which fail each check of the input.
+var badCubes = []errorCase{
+	{"color count", set(solved, map[int]byte{0: 'R'}), 24, twophase.ErrColorCount},
+	{"short", solved[:53], 24, twophase.ErrColorCount},
+	// UF is a second UR, and FR has two front facelets.
+	{"edges", set(solved, map[int]byte{19: 'R', 12: 'F'}), 24, twophase.ErrEdges},
+	// UF flipped.
+	{"flip", set(solved, map[int]byte{7: 'F', 19: 'U'}), 24, twophase.ErrFlip},
+	// URF and UFL with a left and a second front facelet.
+	{"corners", set(solved, map[int]byte{20: 'L', 38: 'F'}), 24, twophase.ErrCorners},
+	// URF twisted.
+	{"twist", set(solved, map[int]byte{8: 'F', 9: 'U', 20: 'R'}), 24, twophase.ErrTwist},
+	// UR and UF swapped.
+	{"parity", set(solved, map[int]byte{10: 'F', 19: 'R'}), 24, twophase.ErrParity},
+}
+
+// TestSolution checks the solutions of the input cubes, which are the ones of
+// the C solver.
+func TestSolution(t *testing.T) {
+	cubes := inputCubes(t)
+	for _, tc := range []struct {
+		name     string
+		maxDepth int
+		want     string
+	}{
+		{"in.1", 24, "U D2 F R' U2 L' B2 R U' R B' R U L2 F2 U2 B2 D F2 U2 F2 D "},
+		{"in.1", 21, "U' R D' F B2 R F2 D2 L' B L F D2 B2 D' B2 R2 U2 D' L2 D "},
+		{"in.2", 24, "U B' U2 F2 R U' L2 D R L' D2 B L2 D B2 D L2 U B2 D2 R2 F2 "},
+		{"in.2", 21, "D B' L U' F2 U2 D B L F2 R F U F2 R2 U D R2 L2 D L2 "},
+		{"in.3", 24, "U2 F' U' R' F R' B' L U' L2 B U D R2 U L2 U F2 D R2 U "},
+		{"in.4", 24, "L' U' R' F2 R2 U F D "},
+		{"demo.in", 24, "F2 R U L D "},
+		{"demo.in", 5, "F2 R U L D "},
+		{"input.txt", 21, "L' B L D' R' U' L U2 B' L B2 D R2 L2 D L2 F2 U2 D' R2 "},
+	} {
+		got, err := twophase.Solution(cubes[tc.name], tc.maxDepth, time.Minute, false, cacheDir)
+		if err != nil || got != tc.want {
+			t.Errorf("Solution(%s, %d) = %q, %v, want %q", tc.name, tc.maxDepth, got, err, tc.want)
+		}
+	}
+}
+
+// TestSolutionErrors checks the error codes of bad cubes, of a depth limit and
+// of a timeout, and the codes of package solver they map to.
+func TestSolutionErrors(t *testing.T) {
+	native, err := solver.NewNative(cacheDir)
+	if err != nil {
+		t.Fatalf("NewNative() error: %v", err)
+	}
+	cubes := inputCubes(t)
+	cases := append([]errorCase{{"max depth", cubes["in.1"], 5, twophase.ErrNoSolution}}, badCubes...)
+	for _, tc := range cases {
+		got, err := twophase.Solution(tc.facelets, tc.maxDepth, time.Minute, false, cacheDir)
+		if err != tc.err {
+			t.Errorf("%s: Solution(%s) = %q, %v, want %v", tc.name, tc.facelets, got, err, tc.err)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "Error ") {
+			t.Errorf("%s: Solution(%s) error %q, want it formatted like the C solver", tc.name, tc.facelets, err)
+		}
+		_, err = native.Solve(context.Background(), tc.facelets, solver.Options{MaxDepth: tc.maxDepth})
+		var e *solver.Error
+		if !errors.As(err, &e) || e.Code != solver.Code(tc.err) {
+			t.Errorf("%s: Native.Solve(%s) error: %v, want code %d", tc.name, tc.facelets, err, tc.err)
+		}
+	}
+
+	// in.2 takes seconds to solve in 20 moves.
+	if _, err := twophase.Solution(cubes["in.2"], 20, time.Millisecond, false, cacheDir); err != twophase.ErrTimeout {
+		t.Errorf("Solution(in.2) in 1ms error: %v, want %v", err, twophase.ErrTimeout)
+	}
+	_, err = native.Solve(context.Background(), cubes["in.2"], solver.Options{MaxDepth: 20, Timeout: time.Millisecond})
+	var e *solver.Error
+	if !errors.As(err, &e) || e.Code != solver.CodeTimeout {
+		t.Errorf("Native.Solve(in.2) in 1ms error: %v, want code %d", err, solver.CodeTimeout)
+	}
+}
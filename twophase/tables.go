@@ -0,0 +1,249 @@
+package twophase
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Number of values of the coordinates.
+const (
+	nTwist    = 2187
+	nFlip     = 2048
+	nSlice1   = 495
+	nSlice2   = 24
+	nParity   = 2
+	nURFtoDLF = 20160
+	nFRtoBR   = 11880
+	nURtoUL   = 1320
+	nUBtoDF   = 1320
+	nURtoDF   = 20160
+	nMove     = 18
+)
+
+// Move tables, see kociemba/include/coordcube.h for the meaning of each.
+var (
+	twistMove            [nTwist][nMove]int16
+	flipMove             [nFlip][nMove]int16
+	FRtoBRMove           [nFRtoBR][nMove]int16
+	URFtoDLFMove         [nURFtoDLF][nMove]int16
+	URtoDFMove           [nURtoDF][nMove]int16
+	URtoULMove           [nURtoUL][nMove]int16
+	UBtoDFMove           [nUBtoDF][nMove]int16
+	mergeURtoULandUBtoDF [336][336]int16
+
+	// Parity of the corner permutation. This is the same as the parity for the
+	// edge permutation of a valid cube.
+	parityMove = [2][18]int16{
+		{1, 0, 1, 1, 0, 1, 1, 0, 1, 1, 0, 1, 1, 0, 1, 1, 0, 1},
+		{0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0},
+	}
+)
+
+// Pruning tables, two values are stored in one byte.
+var (
+	sliceURFtoDLFParityPrun [nSlice2 * nURFtoDLF * nParity / 2]int8
+	sliceURtoDFParityPrun   [nSlice2 * nURtoDF * nParity / 2]int8
+	sliceTwistPrun          [nSlice1*nTwist/2 + 1]int8
+	sliceFlipPrun           [nSlice1 * nFlip / 2]int8
+)
+
+var (
+	initOnce sync.Once
+	initErr  error
+)
+
+// InitTables loads the move and pruning tables from cacheDir, or computes them
+// and saves them to cacheDir if they're not cached. The cache files are the
+// same as the ones of the C solver in kociemba/. Tables are loaded once per
+// process, an empty cacheDir disables the cache.
+//
+// Solution calls InitTables, call it before to pay the cost at startup.
+func InitTables(cacheDir string) error {
+	initOnce.Do(func() {
+		initErr = initTables(cacheDir)
+	})
+	return initErr
+}
+
+// table loads data from cacheDir/name, or computes it with gen and saves it.
+func table(cacheDir, name string, data interface{}, gen func()) error {
+	if cacheDir == "" {
+		gen()
+		return nil
+	}
+	fname := filepath.Join(cacheDir, name)
+	if f, err := os.Open(fname); err == nil {
+		defer f.Close()
+		if err := binary.Read(f, binary.LittleEndian, data); err != nil {
+			return fmt.Errorf("can't read cache table %s: %v", fname, err)
+		}
+		return nil
+	}
+	log.Printf("Cache table %s was not found. Recalculating.", fname)
+	gen()
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("cannot create cache tables directory: %v", err)
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(f, binary.LittleEndian, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// moveTable fills a move table for a coordinate: set sets the coordinate on
+// a cube, get reads it back and mul applies a face turn to the cube.
+func moveTable(tab [][nMove]int16, set func(*cubieCube, int), get func(*cubieCube) int, mul func(*cubieCube, *cubieCube)) {
+	a := newCubieCube()
+	for i := range tab {
+		set(a, i)
+		for j := 0; j < 6; j++ {
+			for k := 0; k < 3; k++ {
+				mul(a, &moveCube[j])
+				tab[i][3*j+k] = int16(get(a))
+			}
+			mul(a, &moveCube[j]) // 4. faceturn restores
+		}
+	}
+}
+
+// isPhase2Move reports whether move m is allowed in phase 2: U, D, R2, F2, L2
+// and B2.
+func isPhase2Move(m int) bool {
+	switch m {
+	case 3, 5, 6, 8, 12, 14, 15, 17:
+		return false
+	}
+	return true
+}
+
+// pruneTable fills a pruning table by breadth first search from the solved
+// state. next returns the index reached by move m from index i, or -1 if the
+// move isn't allowed.
+func pruneTable(tab []int8, n int, next func(i, m int) int) {
+	for i := range tab {
+		tab[i] = -1
+	}
+	setPruning(tab, 0, 0)
+	for depth, done := 0, 1; done != n; depth++ {
+		for i := 0; i < n; i++ {
+			if getPruning(tab, i) != int8(depth) {
+				continue
+			}
+			for m := 0; m < nMove; m++ {
+				j := next(i, m)
+				if j >= 0 && getPruning(tab, j) == 0x0f {
+					setPruning(tab, j, int8(depth+1))
+					done++
+				}
+			}
+		}
+	}
+}
+
+func initTables(cacheDir string) error {
+	edges := (*cubieCube).edgeMultiply
+	corners := (*cubieCube).cornerMultiply
+
+	tables := []struct {
+		name string
+		data interface{}
+		gen  func()
+	}{
+		{"twistMove", &twistMove, func() {
+			moveTable(twistMove[:], (*cubieCube).setTwist, (*cubieCube).twist, corners)
+		}},
+		{"flipMove", &flipMove, func() {
+			moveTable(flipMove[:], (*cubieCube).setFlip, (*cubieCube).flip, edges)
+		}},
+		{"FRtoBR_Move", &FRtoBRMove, func() {
+			moveTable(FRtoBRMove[:], (*cubieCube).setFRtoBR, (*cubieCube).FRtoBR, edges)
+		}},
+		{"URFtoDLF_Move", &URFtoDLFMove, func() {
+			moveTable(URFtoDLFMove[:], (*cubieCube).setURFtoDLF, (*cubieCube).URFtoDLF, corners)
+		}},
+		// Table values are only valid for phase 2 moves, for phase 1 moves
+		// the coordinate doesn't fit in an int16.
+		{"URtoDF_Move", &URtoDFMove, func() {
+			moveTable(URtoDFMove[:], (*cubieCube).setURtoDF, (*cubieCube).URtoDF, edges)
+		}},
+		{"URtoUL_Move", &URtoULMove, func() {
+			moveTable(URtoULMove[:], (*cubieCube).setURtoUL, (*cubieCube).URtoUL, edges)
+		}},
+		{"UBtoDF_Move", &UBtoDFMove, func() {
+			moveTable(UBtoDFMove[:], (*cubieCube).setUBtoDF, (*cubieCube).UBtoDF, edges)
+		}},
+		{"MergeURtoULandUBtoDF", &mergeURtoULandUBtoDF, func() {
+			// for i, j <336 the six edges UR,UF,UL,UB,DR,DF are not in the
+			// UD-slice and the index is <20160
+			for i := 0; i < 336; i++ {
+				for j := 0; j < 336; j++ {
+					mergeURtoULandUBtoDF[i][j] = int16(mergeURtoDF(i, j))
+				}
+			}
+		}},
+		{"Slice_URFtoDLF_Parity_Prun", &sliceURFtoDLFParityPrun, func() {
+			pruneTable(sliceURFtoDLFParityPrun[:], nSlice2*nURFtoDLF*nParity, func(i, m int) int {
+				if !isPhase2Move(m) {
+					return -1
+				}
+				parity, URFtoDLF, slice := i%2, (i/2)/nSlice2, (i/2)%nSlice2
+				return (nSlice2*int(URFtoDLFMove[URFtoDLF][m])+int(FRtoBRMove[slice][m]))*2 + int(parityMove[parity][m])
+			})
+		}},
+		{"Slice_URtoDF_Parity_Prun", &sliceURtoDFParityPrun, func() {
+			pruneTable(sliceURtoDFParityPrun[:], nSlice2*nURtoDF*nParity, func(i, m int) int {
+				if !isPhase2Move(m) {
+					return -1
+				}
+				parity, URtoDF, slice := i%2, (i/2)/nSlice2, (i/2)%nSlice2
+				return (nSlice2*int(URtoDFMove[URtoDF][m])+int(FRtoBRMove[slice][m]))*2 + int(parityMove[parity][m])
+			})
+		}},
+		{"Slice_Twist_Prun", &sliceTwistPrun, func() {
+			pruneTable(sliceTwistPrun[:], nSlice1*nTwist, func(i, m int) int {
+				twist, slice := i/nSlice1, i%nSlice1
+				return nSlice1*int(twistMove[twist][m]) + int(FRtoBRMove[slice*24][m])/24
+			})
+		}},
+		{"Slice_Flip_Prun", &sliceFlipPrun, func() {
+			pruneTable(sliceFlipPrun[:], nSlice1*nFlip, func(i, m int) int {
+				flip, slice := i/nSlice1, i%nSlice1
+				return nSlice1*int(flipMove[flip][m]) + int(FRtoBRMove[slice*24][m])/24
+			})
+		}},
+	}
+	for _, t := range tables {
+		if err := table(cacheDir, t.name, t.data, t.gen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPruning sets a pruning value in the table. Two values are stored in one
+// byte, the table must be initialized to -1.
+func setPruning(table []int8, index int, value int8) {
+	if index&1 == 0 {
+		table[index/2] &= -16 | value // 0xf0 | value
+	} else {
+		table[index/2] &= 0x0f | (value << 4)
+	}
+}
+
+// getPruning extracts a pruning value.
+func getPruning(table []int8, index int) int8 {
+	if index&1 == 0 {
+		return table[index/2] & 0x0f
+	}
+	return (table[index/2] >> 4) & 0x0f
+}